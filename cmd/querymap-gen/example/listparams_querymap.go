@@ -0,0 +1,176 @@
+// Code generated by querymap-gen; DO NOT EDIT.
+
+package example
+
+import (
+	"github.com/KoNekoD/go-querymap/pkg/querymap/genruntime"
+	"net/url"
+	"strconv"
+)
+
+// DecodeQuery decodes the query parameters into the structure, see querymap.FromValuesToStruct.
+// Returns *querymap.DecodeError pointing to the query parameters that can't be decoded.
+func (s *ListParams) DecodeQuery(values url.Values) error {
+	d := genruntime.NewDecoder(values)
+	s.decodeQuery(d, nil)
+	return d.Err()
+}
+
+// EncodeQuery encodes the structure into query parameters, see querymap.StructToValues.
+func (s *ListParams) EncodeQuery() url.Values {
+	values := make(url.Values)
+	s.encodeQuery(values, "")
+	return values
+}
+
+func (s *Filter) decodeQuery(d *genruntime.Decoder, path []string) {
+	if v, ok := genruntime.DecodeValue(d, genruntime.Field{Parent: path, Name: "status", Type: "example.Status"}, genruntime.ParseString[Status]); ok {
+		s.Status = v
+	}
+	if v, ok := genruntime.DecodeList(d, genruntime.Field{Parent: path, Name: "statuses", Type: "[]example.Status", Delimiter: "|"}, genruntime.ParseString[Status]); ok {
+		s.Statuses = v
+	}
+	if v, ok := genruntime.DecodeValue(d, genruntime.Field{Parent: path, Name: "ratio", Type: "*float32"}, genruntime.ParseFloat[float32]); ok {
+		s.Ratio = &v
+	}
+	if v, ok := genruntime.DecodeList(d, genruntime.Field{Parent: path, Name: "tags", Type: "[]string"}, genruntime.ParseString[string]); ok {
+		s.Tags = v
+	}
+	if childPath := append(path[:len(path):len(path)], "owner"); d.Has(childPath) {
+		if s.Owner == nil {
+			s.Owner = new(Owner)
+		}
+		s.Owner.decodeQuery(d, childPath)
+	}
+	if v, ok := genruntime.DecodeValue(d, genruntime.Field{Parent: path, Name: "Deadline", Type: "time.Duration"}, genruntime.ParseDuration); ok {
+		s.Deadline = v
+	}
+}
+
+func (s *Filter) encodeQuery(values url.Values, prefix string) {
+	values.Add(genruntime.Key(prefix, "status"), string(s.Status))
+	if len(s.Statuses) > 0 {
+		list := make([]string, len(s.Statuses))
+		for i, v := range s.Statuses {
+			list[i] = string(v)
+		}
+		genruntime.EncodeList(values, genruntime.Key(prefix, "statuses"), list, "|")
+	}
+	if s.Ratio != nil {
+		values.Add(genruntime.Key(prefix, "ratio"), strconv.FormatFloat(float64(*s.Ratio), 'f', -1, 32))
+	}
+	if len(s.Tags) > 0 {
+		list := make([]string, len(s.Tags))
+		for i, v := range s.Tags {
+			list[i] = v
+		}
+		genruntime.EncodeList(values, genruntime.Key(prefix, "tags"), list, "")
+	}
+	if s.Owner != nil {
+		s.Owner.encodeQuery(values, genruntime.Key(prefix, "owner"))
+	}
+	values.Add(genruntime.Key(prefix, "Deadline"), strconv.FormatInt(int64(s.Deadline), 10))
+}
+
+func (s *ListParams) decodeQuery(d *genruntime.Decoder, path []string) {
+	if v, ok := genruntime.DecodeValue(d, genruntime.Field{Parent: path, Name: "page", Type: "int", Default: "1", HasDefault: true}, genruntime.ParseInt[int]); ok {
+		s.Page = v
+	}
+	if v, ok := genruntime.DecodeValue(d, genruntime.Field{Parent: path, Name: "limit", Type: "uint16", Default: "20", HasDefault: true}, genruntime.ParseUint[uint16]); ok {
+		s.Limit = v
+	}
+	if v, ok := genruntime.DecodeValue(d, genruntime.Field{Parent: path, Name: "q", Type: "string"}, genruntime.ParseString[string]); ok {
+		s.Query = v
+	}
+	if v, ok := genruntime.DecodeList(d, genruntime.Field{Parent: path, Name: "sort", Type: "[]string", Delimiter: ","}, genruntime.ParseString[string]); ok {
+		s.Sort = v
+	}
+	if v, ok := genruntime.DecodeList(d, genruntime.Field{Parent: path, Name: "ids", Type: "[]int64"}, genruntime.ParseInt[int64]); ok {
+		s.IDs = v
+	}
+	if v, ok := genruntime.DecodeValue(d, genruntime.Field{Parent: path, Name: "active", Type: "*bool"}, genruntime.ParseBool[bool]); ok {
+		s.Active = &v
+	}
+	if v, ok := genruntime.DecodeValue(d, genruntime.Field{Parent: path, Name: "min_price", Type: "float64"}, genruntime.ParseFloat[float64]); ok {
+		s.MinPrice = v
+	}
+	if v, ok := genruntime.DecodeValue(d, genruntime.Field{Parent: path, Name: "timeout", Type: "time.Duration"}, genruntime.ParseDuration); ok {
+		s.Timeout = v
+	}
+	if v, ok := genruntime.DecodeValue(d, genruntime.Field{Parent: path, Name: "token", Type: "string", Required: true}, genruntime.ParseString[string]); ok {
+		s.Token = v
+	}
+	s.Filter.decodeQuery(d, append(path[:len(path):len(path)], "filter"))
+	if childPath := append(path[:len(path):len(path)], "owner"); d.Has(childPath) {
+		if s.Owner == nil {
+			s.Owner = new(Owner)
+		}
+		s.Owner.decodeQuery(d, childPath)
+	}
+	s.Paging.decodeQuery(d, path)
+}
+
+func (s *ListParams) encodeQuery(values url.Values, prefix string) {
+	values.Add(genruntime.Key(prefix, "page"), strconv.FormatInt(int64(s.Page), 10))
+	values.Add(genruntime.Key(prefix, "limit"), strconv.FormatUint(uint64(s.Limit), 10))
+	if s.Query != "" {
+		values.Add(genruntime.Key(prefix, "q"), s.Query)
+	}
+	if len(s.Sort) > 0 {
+		list := make([]string, len(s.Sort))
+		for i, v := range s.Sort {
+			list[i] = v
+		}
+		genruntime.EncodeList(values, genruntime.Key(prefix, "sort"), list, ",")
+	}
+	if len(s.IDs) > 0 {
+		list := make([]string, len(s.IDs))
+		for i, v := range s.IDs {
+			list[i] = strconv.FormatInt(int64(v), 10)
+		}
+		genruntime.EncodeList(values, genruntime.Key(prefix, "ids"), list, "")
+	}
+	if s.Active != nil {
+		values.Add(genruntime.Key(prefix, "active"), strconv.FormatBool(bool(*s.Active)))
+	}
+	if s.MinPrice != 0 {
+		values.Add(genruntime.Key(prefix, "min_price"), strconv.FormatFloat(float64(s.MinPrice), 'f', -1, 64))
+	}
+	if s.Timeout != 0 {
+		values.Add(genruntime.Key(prefix, "timeout"), strconv.FormatInt(int64(s.Timeout), 10))
+	}
+	values.Add(genruntime.Key(prefix, "token"), s.Token)
+	s.Filter.encodeQuery(values, genruntime.Key(prefix, "filter"))
+	if s.Owner != nil {
+		s.Owner.encodeQuery(values, genruntime.Key(prefix, "owner"))
+	}
+	s.Paging.encodeQuery(values, prefix)
+}
+
+func (s *Owner) decodeQuery(d *genruntime.Decoder, path []string) {
+	if v, ok := genruntime.DecodeValue(d, genruntime.Field{Parent: path, Name: "id", Type: "int"}, genruntime.ParseInt[int]); ok {
+		s.ID = v
+	}
+	if v, ok := genruntime.DecodeValue(d, genruntime.Field{Parent: path, Name: "name", Type: "string"}, genruntime.ParseString[string]); ok {
+		s.Name = v
+	}
+}
+
+func (s *Owner) encodeQuery(values url.Values, prefix string) {
+	values.Add(genruntime.Key(prefix, "id"), strconv.FormatInt(int64(s.ID), 10))
+	if s.Name != "" {
+		values.Add(genruntime.Key(prefix, "name"), s.Name)
+	}
+}
+
+func (s *Paging) decodeQuery(d *genruntime.Decoder, path []string) {
+	if v, ok := genruntime.DecodeValue(d, genruntime.Field{Parent: path, Name: "cursor", Type: "string"}, genruntime.ParseString[string]); ok {
+		s.Cursor = v
+	}
+}
+
+func (s *Paging) encodeQuery(values url.Values, prefix string) {
+	if s.Cursor != "" {
+		values.Add(genruntime.Key(prefix, "cursor"), s.Cursor)
+	}
+}
@@ -0,0 +1,47 @@
+// Package example shows the code generated by querymap-gen, see listparams_querymap.go.
+package example
+
+import "time"
+
+//go:generate go run github.com/KoNekoD/go-querymap/cmd/querymap-gen -type ListParams
+
+// Status is a named string type.
+type Status string
+
+// ListParams are the parameters of a list endpoint.
+type ListParams struct {
+	Page     int           `query:"page,default=1"`
+	Limit    uint16        `query:"limit,default=20"`
+	Query    string        `query:"q,omitempty"`
+	Sort     []string      `query:"sort,comma"`
+	IDs      []int64       `json:"ids"`
+	Active   *bool         `query:"active"`
+	MinPrice float64       `query:"min_price,omitempty"`
+	Timeout  time.Duration `query:"timeout,omitempty"`
+	Token    string        `query:"token,required"`
+	Filter   Filter        `query:"filter"`
+	Owner    *Owner        `query:"owner"`
+	Paging   `query:",inline"`
+	Internal string `query:"-"`
+}
+
+// Filter is a nested structure.
+type Filter struct {
+	Status   Status   `query:"status"`
+	Statuses []Status `query:"statuses,pipe"`
+	Ratio    *float32 `query:"ratio"`
+	Tags     []string `query:"tags"`
+	Owner    *Owner   `query:"owner"`
+	Deadline time.Duration
+}
+
+// Owner is a structure used by pointers.
+type Owner struct {
+	ID   int    `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// Paging is an inline structure.
+type Paging struct {
+	Cursor string `query:"cursor,omitempty"`
+}
@@ -0,0 +1,112 @@
+package example
+
+import (
+	"github.com/KoNekoD/go-querymap/pkg/querymap"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// The generated methods must behave the same way as querymap.FromValuesToStruct and querymap.StructToValues.
+
+func TestDecodeQueryMatchesFromValuesToStruct(t *testing.T) {
+	queries := []string{
+		"token=t",
+		"token=t&page=3&limit=50&q=shoes&sort=name,-date&ids[]=1&ids[]=2&active=true&min_price=9.5&timeout=5s",
+		"token=t&ids=3&ids=1&ids=2",
+		"token=t&ids[1]=2&ids[0]=1",
+		"token=t&filter[status]=open&filter[statuses]=a|b&filter[ratio]=0.5&filter[tags][]=x&filter[Deadline]=1m",
+		"token=t&filter[owner][id]=7&owner[id]=8&owner[name]=Ken&cursor=abc",
+		"TOKEN=t&PAGE=2&Filter[Status]=closed",
+		"token=t&active=",
+		"token=t&page=0x10&limit=",
+		"page=x",
+		"token=t&page=1&page=2",
+		"token=t&page[]=1",
+		"token=t&limit=-1&ids[]=1&ids[]=x&active=maybe&timeout=soon&filter[ratio]=1e100",
+	}
+	for _, query := range queries {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want, wantErr := querymap.FromValuesToStruct[ListParams](values)
+
+		var got ListParams
+		gotErr := got.DecodeQuery(values)
+
+		if (wantErr == nil) != (gotErr == nil) || wantErr != nil && wantErr.Error() != gotErr.Error() {
+			t.Errorf("%s: DecodeQuery() error = %v, want %v", query, gotErr, wantErr)
+			continue
+		}
+		if wantErr == nil && !reflect.DeepEqual(got, *want) {
+			t.Errorf("%s: DecodeQuery() = %+v, want %+v", query, got, *want)
+		}
+	}
+}
+
+func TestEncodeQueryMatchesStructToValues(t *testing.T) {
+	active, ratio := false, float32(0.25)
+	params := []ListParams{
+		{},
+		{
+			Page: 2, Limit: 10, Query: "red shoes", Sort: []string{"name", "-date"}, IDs: []int64{3, 1},
+			Active: &active, MinPrice: 1.5, Timeout: 5 * time.Second, Token: "t",
+			Filter: Filter{
+				Status: "open", Statuses: []Status{"a", "b"}, Ratio: &ratio, Tags: []string{"x"},
+				Owner: &Owner{ID: 1}, Deadline: time.Minute,
+			},
+			Owner:    &Owner{ID: 2, Name: "Ken"},
+			Paging:   Paging{Cursor: "abc"},
+			Internal: "secret",
+		},
+	}
+	for _, p := range params {
+		want, err := querymap.StructToValues(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := p.EncodeQuery()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("EncodeQuery() = %v, want %v", got, want)
+		}
+
+		var decoded ListParams
+		if err := decoded.DecodeQuery(got); err != nil {
+			t.Fatal(err)
+		}
+		p.Internal = ""
+		if !reflect.DeepEqual(decoded, p) {
+			t.Errorf("DecodeQuery(EncodeQuery()) = %+v, want %+v", decoded, p)
+		}
+	}
+}
+
+func BenchmarkDecodeQuery(b *testing.B) {
+	values, _ := url.ParseQuery("token=t&page=3&limit=50&sort=name,-date&ids[]=1&ids[]=2&filter[status]=open&owner[id]=8")
+
+	b.Run(
+		"FromValuesToStruct", func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				if _, err := querymap.FromValuesToStruct[ListParams](values); err != nil {
+					b.Fatal(err)
+				}
+			}
+		},
+	)
+	b.Run(
+		"DecodeQuery", func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				var p ListParams
+				if err := p.DecodeQuery(values); err != nil {
+					b.Fatal(err)
+				}
+			}
+		},
+	)
+}
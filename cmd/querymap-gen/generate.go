@@ -0,0 +1,602 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// kind is the decoding kind of a scalar type.
+type kind int
+
+const (
+	kindString kind = iota
+	kindBool
+	kindInt
+	kindUint
+	kindFloat
+	kindDuration
+	kindStruct
+)
+
+// basicKinds are the kinds of the predeclared types.
+var basicKinds = map[string]kind{
+	"string": kindString, "bool": kindBool,
+	"int": kindInt, "int8": kindInt, "int16": kindInt, "int32": kindInt, "int64": kindInt, "rune": kindInt,
+	"uint": kindUint, "uint8": kindUint, "uint16": kindUint, "uint32": kindUint, "uint64": kindUint,
+	"uintptr": kindUint, "byte": kindUint,
+	"float32": kindFloat, "float64": kindFloat,
+}
+
+// fieldType is a resolved field type.
+type fieldType struct {
+	kind kind
+
+	// name is the Go type of the scalar (or of the structure) as written in the package: "int", "Level".
+	name string
+
+	// bits is the size of the floats.
+	bits int
+
+	pointer bool
+	list    bool
+}
+
+// typeString returns the Go type as reported by reflect in the errors of ToStruct: "*int", "[]example.Level".
+func (t fieldType) typeString(pkgName string) string {
+	name := t.name
+	if _, ok := basicKinds[name]; !ok && name != "time.Duration" {
+		name = pkgName + "." + name
+	}
+	switch name {
+	case "byte":
+		name = "uint8"
+	case "rune":
+		name = "int32"
+	}
+
+	switch {
+	case t.pointer:
+		return "*" + name
+	case t.list:
+		return "[]" + name
+	}
+
+	return name
+}
+
+// field is a structure field that can be read from the query, see querymap.structFields.
+type field struct {
+	goName   string
+	name     string
+	typ      fieldType
+	inline   bool
+	def      string
+	hasDef   bool
+	required bool
+	omit     bool
+	delim    string
+}
+
+// generator collects the declarations of a package and writes the methods of its structures.
+type generator struct {
+	pkgName string
+
+	structs map[string]*ast.StructType
+	named   map[string]ast.Expr
+
+	// textTypes are the types with MarshalText or UnmarshalText methods.
+	textTypes map[string]bool
+
+	buf bytes.Buffer
+}
+
+// generate parses the package in `dir` (skipping the test files and the `output` file)
+// and returns the formatted source of the methods of the `types` and of the structures they use.
+func generate(dir, output string, types []string) ([]byte, error) {
+	g := &generator{structs: map[string]*ast.StructType{}, named: map[string]ast.Expr{}, textTypes: map[string]bool{}}
+	if err := g.parsePackage(dir, output); err != nil {
+		return nil, err
+	}
+
+	fields := map[string][]field{}
+	queue := slices.Clone(types)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if _, ok := fields[name]; ok {
+			continue
+		}
+
+		structType, ok := g.structs[name]
+		if !ok {
+			return nil, fmt.Errorf("%s is not a structure type of package %s", name, g.pkgName)
+		}
+
+		structFields, err := g.structFields(name, structType)
+		if err != nil {
+			return nil, err
+		}
+		fields[name] = structFields
+
+		for _, f := range structFields {
+			if f.typ.kind == kindStruct {
+				queue = append(queue, f.typ.name)
+			}
+		}
+	}
+
+	g.writeHeader(fields)
+	for _, name := range types {
+		g.writeEntryPoints(name)
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		g.writeDecoder(name, fields[name])
+		g.writeEncoder(name, fields[name])
+	}
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+
+	return src, nil
+}
+
+// parsePackage collects the type declarations and the text marshaling methods of the package.
+func (g *generator) parsePackage(dir, output string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || name == output {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		if g.pkgName == "" {
+			g.pkgName = file.Name.Name
+		}
+
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					typeSpec, ok := spec.(*ast.TypeSpec)
+					if !ok || typeSpec.TypeParams != nil {
+						continue
+					}
+					if structType, ok := typeSpec.Type.(*ast.StructType); ok {
+						g.structs[typeSpec.Name.Name] = structType
+					} else {
+						g.named[typeSpec.Name.Name] = typeSpec.Type
+					}
+				}
+			case *ast.FuncDecl:
+				if d.Recv != nil && (d.Name.Name == "MarshalText" || d.Name.Name == "UnmarshalText") {
+					g.textTypes[receiverName(d.Recv.List[0].Type)] = true
+				}
+			}
+		}
+	}
+
+	if g.pkgName == "" {
+		return fmt.Errorf("no Go files in %s", dir)
+	}
+
+	return nil
+}
+
+// receiverName returns the type name of a method receiver.
+func receiverName(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+
+	return ""
+}
+
+// structFields returns the fields of the structure the same way querymap.structFields does.
+func (g *generator) structFields(structName string, structType *ast.StructType) ([]field, error) {
+	var fields []field
+
+	for _, astField := range structType.Fields.List {
+		var tag reflect.StructTag
+		if astField.Tag != nil {
+			unquoted, err := strconv.Unquote(astField.Tag.Value)
+			if err != nil {
+				return nil, err
+			}
+			tag = reflect.StructTag(unquoted)
+		}
+
+		names := astField.Names
+		if len(names) == 0 {
+			// An embedded field is named by its type
+			names = []*ast.Ident{ast.NewIdent(receiverName(astField.Type))}
+		}
+
+		for _, ident := range names {
+			f, ok, err := g.structField(ident.Name, astField.Type, tag)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", structName, ident.Name, err)
+			}
+			if ok {
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+// structField resolves a single field, returns false for the fields skipped by the tags or unexported.
+func (g *generator) structField(goName string, expr ast.Expr, tag reflect.StructTag) (field, bool, error) {
+	jsonTag := tag.Get("json")
+	queryTag, hasQueryTag := tag.Lookup("query")
+	if hasQueryTag && queryTag == "-" || !hasQueryTag && jsonTag == "-" {
+		return field{}, false, nil
+	}
+
+	jsonName, jsonOpts := parseTag(jsonTag)
+	queryName, queryOpts := parseTag(queryTag)
+
+	f := field{goName: goName, name: queryName}
+	if f.name == "" {
+		f.name = jsonName
+	}
+	if f.name == "" {
+		f.name = goName
+	}
+	opts := jsonOpts
+	if hasQueryTag {
+		opts = queryOpts
+	}
+
+	squash := hasOption(jsonOpts, "squash")
+	if !ast.IsExported(goName) && !squash {
+		return field{}, false, nil
+	}
+
+	typ, err := g.resolve(expr)
+	if err != nil {
+		return field{}, false, err
+	}
+	f.typ = typ
+
+	if typ.kind == kindStruct {
+		f.inline = squash && !hasQueryTag ||
+			hasOption(opts, "inline") || hasOption(opts, "squash") || hasOption(opts, "explode")
+	} else if !ast.IsExported(goName) {
+		return field{}, false, nil
+	}
+	if f.inline && typ.pointer {
+		return field{}, false, fmt.Errorf("unsupported inline pointer %s", exprString(expr))
+	}
+
+	f.def, f.hasDef = lookupOption(opts, "default")
+	f.required = hasOption(opts, "required")
+	f.omit = hasOption(opts, "omitempty")
+	if typ.list {
+		f.delim = tagDelimiter(opts)
+	}
+
+	return f, true, nil
+}
+
+// resolve resolves the type of a field, returns an error for the unsupported types.
+func (g *generator) resolve(expr ast.Expr) (fieldType, error) {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		t, err := g.resolve(e.X)
+		if err != nil {
+			return t, err
+		}
+		if t.pointer || t.list {
+			return t, fmt.Errorf("unsupported type %s", exprString(expr))
+		}
+		t.pointer = true
+		return t, nil
+	case *ast.ArrayType:
+		if e.Len != nil {
+			return fieldType{}, fmt.Errorf("unsupported array type %s", exprString(expr))
+		}
+		t, err := g.resolve(e.Elt)
+		if err != nil {
+			return t, err
+		}
+		if t.pointer || t.list || t.kind == kindStruct || t.name == "byte" || t.name == "uint8" {
+			return t, fmt.Errorf("unsupported type %s", exprString(expr))
+		}
+		t.list = true
+		return t, nil
+	case *ast.SelectorExpr:
+		if exprString(expr) == "time.Duration" {
+			return fieldType{kind: kindDuration, name: "time.Duration"}, nil
+		}
+	case *ast.Ident:
+		if k, ok := basicKinds[e.Name]; ok {
+			t := fieldType{kind: k, name: e.Name, bits: 64}
+			if e.Name == "float32" {
+				t.bits = 32
+			}
+			return t, nil
+		}
+		if g.textTypes[e.Name] {
+			return fieldType{}, fmt.Errorf("unsupported type %s: encoding.TextUnmarshaler types need reflection", e.Name)
+		}
+		if _, ok := g.structs[e.Name]; ok {
+			return fieldType{kind: kindStruct, name: e.Name}, nil
+		}
+		if underlying, ok := g.named[e.Name]; ok {
+			t, err := g.resolve(underlying)
+			if err != nil || t.pointer || t.list || t.kind == kindStruct {
+				return t, fmt.Errorf("unsupported type %s", e.Name)
+			}
+			// Only time.Duration itself is decoded as a duration
+			if t.kind == kindDuration {
+				t.kind, t.bits = kindInt, 64
+			}
+			t.name = e.Name
+			return t, nil
+		}
+	}
+
+	return fieldType{}, fmt.Errorf("unsupported type %s", exprString(expr))
+}
+
+// exprString formats the type expression.
+func exprString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	_ = format.Node(&buf, token.NewFileSet(), expr)
+	return buf.String()
+}
+
+// writeHeader writes the package clause and the imports.
+func (g *generator) writeHeader(fields map[string][]field) {
+	usesStrconv := false
+	for _, structFields := range fields {
+		for _, f := range structFields {
+			usesStrconv = usesStrconv || f.typ.kind != kindString && f.typ.kind != kindStruct
+		}
+	}
+
+	g.printf("// Code generated by querymap-gen; DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", g.pkgName)
+	g.printf("import (\n")
+	g.printf("\t\"github.com/KoNekoD/go-querymap/pkg/querymap/genruntime\"\n")
+	g.printf("\t\"net/url\"\n")
+	if usesStrconv {
+		g.printf("\t\"strconv\"\n")
+	}
+	g.printf(")\n")
+}
+
+// writeEntryPoints writes the exported DecodeQuery and EncodeQuery methods of the structure.
+func (g *generator) writeEntryPoints(name string) {
+	g.printf("\n// DecodeQuery decodes the query parameters into the structure, see querymap.FromValuesToStruct.\n")
+	g.printf("// Returns *querymap.DecodeError pointing to the query parameters that can't be decoded.\n")
+	g.printf("func (s *%s) DecodeQuery(values url.Values) error {\n", name)
+	g.printf("\td := genruntime.NewDecoder(values)\n")
+	g.printf("\ts.decodeQuery(d, nil)\n")
+	g.printf("\treturn d.Err()\n")
+	g.printf("}\n")
+
+	g.printf("\n// EncodeQuery encodes the structure into query parameters, see querymap.StructToValues.\n")
+	g.printf("func (s *%s) EncodeQuery() url.Values {\n", name)
+	g.printf("\tvalues := make(url.Values)\n")
+	g.printf("\ts.encodeQuery(values, \"\")\n")
+	g.printf("\treturn values\n")
+	g.printf("}\n")
+}
+
+// writeDecoder writes the decodeQuery method of the structure.
+func (g *generator) writeDecoder(name string, fields []field) {
+	g.printf("\nfunc (s *%s) decodeQuery(d *genruntime.Decoder, path []string) {\n", name)
+
+	for _, f := range fields {
+		target := "s." + f.goName
+
+		if f.typ.kind == kindStruct {
+			childPath := fmt.Sprintf("append(path[:len(path):len(path)], %q)", f.name)
+			if f.inline {
+				childPath = "path"
+			}
+			if !f.typ.pointer {
+				g.printf("\t%s.decodeQuery(d, %s)\n", target, childPath)
+				continue
+			}
+			g.printf("\tif childPath := %s; d.Has(childPath) {\n", childPath)
+			g.printf("\t\tif %s == nil {\n\t\t\t%s = new(%s)\n\t\t}\n", target, target, f.typ.name)
+			g.printf("\t\t%s.decodeQuery(d, childPath)\n", target)
+			g.printf("\t}\n")
+			continue
+		}
+
+		decode := "DecodeValue"
+		if f.typ.list {
+			decode = "DecodeList"
+		}
+		g.printf("\tif v, ok := genruntime.%s(d, %s, %s); ok {\n", decode, g.fieldLiteral(f), parseFunc(f.typ))
+		if f.typ.pointer {
+			g.printf("\t\t%s = &v\n", target)
+		} else {
+			g.printf("\t\t%s = v\n", target)
+		}
+		g.printf("\t}\n")
+	}
+
+	g.printf("}\n")
+}
+
+// fieldLiteral returns the genruntime.Field literal of the field.
+func (g *generator) fieldLiteral(f field) string {
+	literal := fmt.Sprintf("genruntime.Field{Parent: path, Name: %q, Type: %q", f.name, f.typ.typeString(g.pkgName))
+	if f.hasDef {
+		literal += fmt.Sprintf(", Default: %q, HasDefault: true", f.def)
+	}
+	if f.required {
+		literal += ", Required: true"
+	}
+	if f.delim != "" {
+		literal += fmt.Sprintf(", Delimiter: %q", f.delim)
+	}
+
+	return literal + "}"
+}
+
+// parseFunc returns the genruntime parse function of the scalar type.
+func parseFunc(t fieldType) string {
+	switch t.kind {
+	case kindBool:
+		return "genruntime.ParseBool[" + t.name + "]"
+	case kindInt:
+		return "genruntime.ParseInt[" + t.name + "]"
+	case kindUint:
+		return "genruntime.ParseUint[" + t.name + "]"
+	case kindFloat:
+		return "genruntime.ParseFloat[" + t.name + "]"
+	case kindDuration:
+		return "genruntime.ParseDuration"
+	}
+
+	return "genruntime.ParseString[" + t.name + "]"
+}
+
+// writeEncoder writes the encodeQuery method of the structure.
+func (g *generator) writeEncoder(name string, fields []field) {
+	g.printf("\nfunc (s *%s) encodeQuery(values url.Values, prefix string) {\n", name)
+
+	for _, f := range fields {
+		target := "s." + f.goName
+		key := fmt.Sprintf("genruntime.Key(prefix, %q)", f.name)
+
+		switch {
+		case f.typ.kind == kindStruct:
+			if f.inline {
+				key = "prefix"
+			}
+			if f.typ.pointer {
+				g.printf("\tif %s != nil {\n\t\t%s.encodeQuery(values, %s)\n\t}\n", target, target, key)
+			} else {
+				g.printf("\t%s.encodeQuery(values, %s)\n", target, key)
+			}
+		case f.typ.list:
+			g.printf("\tif len(%s) > 0 {\n", target)
+			g.printf("\t\tlist := make([]string, len(%s))\n", target)
+			g.printf("\t\tfor i, v := range %s {\n\t\t\tlist[i] = %s\n\t\t}\n", target, formatExpr(f.typ, "v"))
+			g.printf("\t\tgenruntime.EncodeList(values, %s, list, %q)\n", key, f.delim)
+			g.printf("\t}\n")
+		case f.typ.pointer:
+			g.printf("\tif %s != nil {\n", target)
+			g.printf("\t\tvalues.Add(%s, %s)\n", key, formatExpr(f.typ, "*"+target))
+			g.printf("\t}\n")
+		case f.omit:
+			g.printf("\tif %s {\n", nonZeroExpr(f.typ, target))
+			g.printf("\t\tvalues.Add(%s, %s)\n", key, formatExpr(f.typ, target))
+			g.printf("\t}\n")
+		default:
+			g.printf("\tvalues.Add(%s, %s)\n", key, formatExpr(f.typ, target))
+		}
+	}
+
+	g.printf("}\n")
+}
+
+// formatExpr returns the expression formatting the scalar `v` the same way querymap.FromStruct does.
+func formatExpr(t fieldType, v string) string {
+	switch t.kind {
+	case kindBool:
+		return "strconv.FormatBool(bool(" + v + "))"
+	case kindInt, kindDuration:
+		return "strconv.FormatInt(int64(" + v + "), 10)"
+	case kindUint:
+		return "strconv.FormatUint(uint64(" + v + "), 10)"
+	case kindFloat:
+		return fmt.Sprintf("strconv.FormatFloat(float64(%s), 'f', -1, %d)", v, t.bits)
+	}
+
+	if t.name == "string" {
+		return v
+	}
+
+	return "string(" + v + ")"
+}
+
+// nonZeroExpr returns the condition of a non-empty scalar for the `omitempty` option.
+func nonZeroExpr(t fieldType, v string) string {
+	switch t.kind {
+	case kindString:
+		return v + ` != ""`
+	case kindBool:
+		return "bool(" + v + ")"
+	}
+
+	return v + " != 0"
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// parseTag splits a struct field's tag into its name and comma-separated options.
+func parseTag(tag string) (string, string) {
+	name, opts, _ := strings.Cut(tag, ",")
+	return name, opts
+}
+
+// hasOption reports whether a comma-separated list of options contains the option.
+func hasOption(opts, option string) bool {
+	return slices.Contains(strings.Split(opts, ","), option)
+}
+
+// lookupOption returns the value of an option of the form "name=value".
+func lookupOption(opts, option string) (string, bool) {
+	for _, o := range strings.Split(opts, ",") {
+		if value, ok := strings.CutPrefix(o, option+"="); ok {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// tagDelimiter returns the delimiter set by the `comma`, `pipe`, `space` or `delimiter=x` options, if any.
+func tagDelimiter(opts string) string {
+	switch {
+	case hasOption(opts, "comma"):
+		return ","
+	case hasOption(opts, "pipe"):
+		return "|"
+	case hasOption(opts, "space"):
+		return " "
+	}
+
+	delimiter, _ := lookupOption(opts, "delimiter")
+	return delimiter
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateExample(t *testing.T) {
+	got, err := generate("example", "listparams_querymap.go", []string{"ListParams"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := os.ReadFile(filepath.Join("example", "listparams_querymap.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != string(want) {
+		t.Errorf("example/listparams_querymap.go is outdated, run go generate ./cmd/querymap-gen/example")
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "unknown type",
+			source: "type Params struct{}",
+			want:   "Missing is not a structure type of package p",
+		},
+		{
+			name:   "map field",
+			source: "type Missing struct{ M map[string]string }",
+			want:   "Missing.M: unsupported type map[string]string",
+		},
+		{
+			name:   "text unmarshaler",
+			source: "type Missing struct{ L Level }\ntype Level int\nfunc (l *Level) UnmarshalText([]byte) error { return nil }",
+			want:   "Missing.L: unsupported type Level: encoding.TextUnmarshaler types need reflection",
+		},
+		{
+			name:   "slice of structures",
+			source: "type Missing struct{ Items []Item }\ntype Item struct{}",
+			want:   "Missing.Items: unsupported type []Item",
+		},
+		{
+			name:   "inline pointer",
+			source: "type Missing struct{ *Item `query:\",inline\"` }\ntype Item struct{}",
+			want:   "Missing.Item: unsupported inline pointer *Item",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				dir := t.TempDir()
+				source := "package p\n\n" + tt.source + "\n"
+				if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte(source), 0o644); err != nil {
+					t.Fatal(err)
+				}
+
+				_, err := generate(dir, "out.go", []string{"Missing"})
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("generate() error = %v, want %s", err, tt.want)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,51 @@
+// Command querymap-gen generates reflection-free DecodeQuery and EncodeQuery methods
+// for the query parameter structures of a package:
+//
+//	//go:generate go run github.com/KoNekoD/go-querymap/cmd/querymap-gen -type ListParams,Filter
+//
+// DecodeQuery(url.Values) error has the same semantics as querymap.FromValuesToStruct
+// and EncodeQuery() url.Values the same semantics as querymap.StructToValues,
+// without building a QueryMap and without reflection.
+//
+// The supported field types are strings, booleans, integers, floats, time.Duration,
+// the named types of the package based on them, pointers to and slices of these types,
+// and the structures of the package (nested or inline). The `query` and `json` tags
+// and the options of the `query` tag (default, required, inline, omitempty, comma, pipe, space, delimiter)
+// are supported as well. The types implementing encoding.TextUnmarshaler are not supported.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of the structure type names, required")
+	dir := flag.String("dir", ".", "directory of the package")
+	output := flag.String("output", "", "output file name, default <dir>/<type>_querymap.go")
+	flag.Parse()
+
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	types := strings.Split(*typeNames, ",")
+
+	if *output == "" {
+		*output = filepath.Join(*dir, strings.ToLower(types[0])+"_querymap.go")
+	}
+
+	src, err := generate(*dir, filepath.Base(*output), types)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "querymap-gen:", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "querymap-gen:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,72 @@
+package querymap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+)
+
+// Canonical returns the canonical query string of the QueryMap: the same parameters always produce the same string,
+// however the client spelled them. The keys are sorted, the values are percent-encoded the same way url.Values.Encode
+// does and every list of strings is written as "key[]", so "b[1]=x&b[0]=y", "b[]=y&b[]=x" and "b=y&b=x" are all
+// written as "b%5B%5D=y&b%5B%5D=x". The order of the list elements is kept, as well as the difference between a single
+// value ("a=x") and a list of one value ("a[]=x").
+func (q QueryMap) Canonical() string {
+	return canonicalValue(q).(QueryMap).Encode()
+}
+
+// CanonicalHash returns the hex-encoded SHA-256 hash of the Canonical query string, for cache keys and deduplication.
+func (q QueryMap) CanonicalHash() string {
+	sum := sha256.Sum256([]byte(q.Canonical()))
+	return hex.EncodeToString(sum[:])
+}
+
+// Canonicalize parses the raw query string with FromValues and returns its canonical form, see QueryMap.Canonical.
+// Returns the url.ParseQuery error if the query is malformed.
+func Canonicalize(rawQuery string) (string, error) {
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", err
+	}
+
+	return FromValues(values).Canonical(), nil
+}
+
+// CanonicalHash parses the raw query string with FromValues and returns the hash of its canonical form,
+// see QueryMap.CanonicalHash. Returns the url.ParseQuery error if the query is malformed.
+func CanonicalHash(rawQuery string) (string, error) {
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", err
+	}
+
+	return FromValues(values).CanonicalHash(), nil
+}
+
+// canonicalValue returns a copy of the value where every list holding only strings is a []string,
+// which is encoded as "key[]" whether the list was sent with indexes or not.
+func canonicalValue(v any) any {
+	switch value := v.(type) {
+	case QueryMap:
+		result := make(QueryMap, len(value))
+		for key, element := range value {
+			result[key] = canonicalValue(element)
+		}
+		return result
+	case anyList:
+		strs := make([]string, 0, len(value))
+		list := make(anyList, len(value))
+		for i, element := range value {
+			list[i] = canonicalValue(element)
+			if str, ok := list[i].(string); ok {
+				strs = append(strs, str)
+			}
+		}
+		if len(strs) == len(list) {
+			return strs
+		}
+		return list
+	}
+
+	return v
+}
@@ -0,0 +1,105 @@
+package querymap
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		queries []string
+		want    string
+	}{
+		{
+			name:    "list spellings",
+			queries: []string{"b[1]=x&b[0]=y", "b[]=y&b[]=x", "b=y&b=x", "b[0]=y&b[5]=x"},
+			want:    "b[]=y&b[]=x",
+		},
+		{
+			name:    "key order",
+			queries: []string{"c=3&a[y]=2&a[x]=1", "a[x]=1&c=3&a[y]=2", "a%5Bx%5D=1&a%5By%5D=2&c=3"},
+			want:    "a[x]=1&a[y]=2&c=3",
+		},
+		{
+			name:    "percent-encoding",
+			queries: []string{"q=red+shoes&s=%7e", "q=red%20shoes&s=~", "q=%72ed%20shoe%73&s=%7E"},
+			want:    "q=red shoes&s=~",
+		},
+		{
+			name:    "lists of maps",
+			queries: []string{"f[1][s]=b&f[0][s]=a&f[0][t][1]=y&f[0][t][0]=x", "f[0][s]=a&f[0][t][]=x&f[0][t][]=y&f[1][s]=b"},
+			want:    "f[0][s]=a&f[0][t][]=x&f[0][t][]=y&f[1][s]=b",
+		},
+		{
+			name:    "single value",
+			queries: []string{"a=x"},
+			want:    "a=x",
+		},
+		{
+			name:    "list of a single value",
+			queries: []string{"a[]=x", "a[0]=x"},
+			want:    "a[]=x",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var hash string
+				for i, query := range tt.queries {
+					got, err := Canonicalize(query)
+					panicIfErr(err)
+
+					unescaped, err := url.QueryUnescape(got)
+					panicIfErr(err)
+					if unescaped != tt.want {
+						t.Errorf("Canonicalize(%q) = %s, want %s", query, unescaped, tt.want)
+					}
+
+					gotHash, err := CanonicalHash(query)
+					panicIfErr(err)
+					if i > 0 && gotHash != hash {
+						t.Errorf("CanonicalHash(%q) = %s, want %s", query, gotHash, hash)
+					}
+					hash = gotHash
+				}
+			},
+		)
+	}
+}
+
+func TestCanonicalizeDistinguishes(t *testing.T) {
+	pairs := [][2]string{
+		{"a=x&a=y", "a=y&a=x"},
+		{"a=x", "a[]=x"},
+		{"a[b]=x", "a=x"},
+		{"a=x", "a=x&b="},
+	}
+	for _, pair := range pairs {
+		a, err := CanonicalHash(pair[0])
+		panicIfErr(err)
+		b, err := CanonicalHash(pair[1])
+		panicIfErr(err)
+		if a == b {
+			t.Errorf("CanonicalHash(%q) = CanonicalHash(%q), want different hashes", pair[0], pair[1])
+		}
+	}
+}
+
+func TestCanonicalizeError(t *testing.T) {
+	if _, err := Canonicalize("a=%zz"); err == nil {
+		t.Errorf("Canonicalize() error = nil, want an error")
+	}
+	if _, err := CanonicalHash("a=%zz"); err == nil {
+		t.Errorf("CanonicalHash() error = nil, want an error")
+	}
+}
+
+func TestQueryMapCanonicalHash(t *testing.T) {
+	// sha256("a=1")
+	const want = "c22fea5d7428e5cf47ef6354c97c9223c95d6dcdc3e0d2300ff79056b1ff3d85"
+
+	if got := (QueryMap{"a": "1"}).CanonicalHash(); got != want {
+		t.Errorf("CanonicalHash() = %s, want %s", got, want)
+	}
+}
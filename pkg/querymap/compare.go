@@ -0,0 +1,256 @@
+package querymap
+
+import (
+	"fmt"
+	"golang.org/x/exp/maps"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// EqualOptions configures QueryMap.EqualWithOptions.
+type EqualOptions struct {
+	// IgnoreListKind makes a single value equal to a list holding only this value:
+	// "a=x" equals "a[]=x" and the values parsed with ParseOptions.AlwaysSlices.
+	IgnoreListKind bool
+
+	// IgnoreOrder compares the lists regardless of the order of their elements: "a[]=x&a[]=y" equals "a[]=y&a[]=x".
+	IgnoreOrder bool
+}
+
+// ChangeKind is the kind of a Change found by QueryMap.Diff.
+type ChangeKind int
+
+const (
+	// Added is a value present only in the other QueryMap.
+	Added ChangeKind = iota
+
+	// Removed is a value present only in the original QueryMap.
+	Removed
+
+	// Changed is a value that differs between the QueryMaps.
+	Changed
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+
+	return "ChangeKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Change describes a single difference between two QueryMaps, see QueryMap.Diff.
+type Change struct {
+	// Kind is the kind of the change.
+	Kind ChangeKind
+
+	// Path is the bracket path of the changed value, for example "filters[0][status]".
+	Path string
+
+	// Old is the value in the original QueryMap, nil if it was Added.
+	Old any
+
+	// New is the value in the other QueryMap, nil if it was Removed.
+	New any
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("added '%s': '%v'", c.Path, c.New)
+	case Removed:
+		return fmt.Sprintf("removed '%s': '%v'", c.Path, c.Old)
+	}
+
+	return fmt.Sprintf("%s '%s': '%v' => '%v'", c.Kind, c.Path, c.Old, c.New)
+}
+
+// Clone returns a deep copy of the QueryMap, the nested maps and lists are copied as well.
+func (q QueryMap) Clone() QueryMap {
+	if q == nil {
+		return nil
+	}
+
+	return cloneValue(q).(QueryMap)
+}
+
+// cloneValue returns a deep copy of the QueryMap value, the values of unknown types are kept as they are.
+func cloneValue(v any) any {
+	switch value := v.(type) {
+	case []string:
+		return slices.Clone(value)
+	case QueryMap:
+		result := make(QueryMap, len(value))
+		for key, element := range value {
+			result[key] = cloneValue(element)
+		}
+		return result
+	case anyList:
+		result := make(anyList, len(value))
+		for i, element := range value {
+			result[i] = cloneValue(element)
+		}
+		return result
+	}
+
+	return v
+}
+
+// Equal reports whether both QueryMaps hold the same parameters. Unlike reflect.DeepEqual,
+// it compares the lists by their elements: []string{"a"} equals anyList{"a"}. See EqualWithOptions.
+func (q QueryMap) Equal(other QueryMap) bool {
+	return q.EqualWithOptions(other, EqualOptions{})
+}
+
+// EqualWithOptions reports whether both QueryMaps hold the same parameters, compared according to the options.
+func (q QueryMap) EqualWithOptions(other QueryMap, options EqualOptions) bool {
+	return equalValues(q, other, options)
+}
+
+// equalValues compares two values of a QueryMap, see QueryMap.EqualWithOptions.
+func equalValues(a, b any, options EqualOptions) bool {
+	aMap, aIsMap := a.(QueryMap)
+	bMap, bIsMap := b.(QueryMap)
+	if aIsMap || bIsMap {
+		if !aIsMap || !bIsMap || len(aMap) != len(bMap) {
+			return false
+		}
+		for key, aValue := range aMap {
+			bValue, ok := bMap[key]
+			if !ok || !equalValues(aValue, bValue, options) {
+				return false
+			}
+		}
+		return true
+	}
+
+	aList, aIsList := listElements(a)
+	bList, bIsList := listElements(b)
+	switch {
+	case aIsList && bIsList:
+		return equalLists(aList, bList, options)
+	case aIsList && options.IgnoreListKind && len(aList) == 1:
+		return equalValues(aList[0], b, options)
+	case bIsList && options.IgnoreListKind && len(bList) == 1:
+		return equalValues(a, bList[0], options)
+	case aIsList || bIsList:
+		return false
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// equalLists compares the elements of two lists in order, or regardless of it with EqualOptions.IgnoreOrder.
+func equalLists(a, b []any, options EqualOptions) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	if !options.IgnoreOrder {
+		for i := range a {
+			if !equalValues(a[i], b[i], options) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Every element of `a` is matched with a distinct equal element of `b`
+	matched := make([]bool, len(b))
+	for _, aElement := range a {
+		found := false
+		for j, bElement := range b {
+			if !matched[j] && equalValues(aElement, bElement, options) {
+				matched[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// listElements returns the elements of a list ([]string or anyList).
+func listElements(v any) ([]any, bool) {
+	switch value := v.(type) {
+	case []string:
+		elements := make([]any, len(value))
+		for i, str := range value {
+			elements[i] = str
+		}
+		return elements, true
+	case anyList:
+		return value, true
+	}
+
+	return nil, false
+}
+
+// Diff returns the changes turning the QueryMap into the other one, ordered by path.
+// The maps and the lists are compared element by element, so a change is reported for the deepest path that differs:
+// "filters[1][status]" rather than "filters". Lists of different lengths report the extra elements as Added or Removed.
+func (q QueryMap) Diff(other QueryMap) []Change {
+	return diffValues(nil, nil, q, other)
+}
+
+// diffValues appends the changes between the values by the path segments to `changes`.
+func diffValues(changes []Change, segments []string, a, b any) []Change {
+	aMap, aIsMap := a.(QueryMap)
+	bMap, bIsMap := b.(QueryMap)
+	if aIsMap && bIsMap {
+		keys := maps.Keys(aMap)
+		for key := range bMap {
+			if _, ok := aMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			keySegments := append(segments[:len(segments):len(segments)], key)
+			aValue, aOk := aMap[key]
+			bValue, bOk := bMap[key]
+			switch {
+			case !aOk:
+				changes = append(changes, Change{Kind: Added, Path: joinPath(keySegments), New: bValue})
+			case !bOk:
+				changes = append(changes, Change{Kind: Removed, Path: joinPath(keySegments), Old: aValue})
+			default:
+				changes = diffValues(changes, keySegments, aValue, bValue)
+			}
+		}
+		return changes
+	}
+
+	aList, aIsList := listElements(a)
+	bList, bIsList := listElements(b)
+	if aIsList && bIsList {
+		for i := range max(len(aList), len(bList)) {
+			indexSegments := append(segments[:len(segments):len(segments)], strconv.Itoa(i))
+			switch {
+			case i >= len(aList):
+				changes = append(changes, Change{Kind: Added, Path: joinPath(indexSegments), New: bList[i]})
+			case i >= len(bList):
+				changes = append(changes, Change{Kind: Removed, Path: joinPath(indexSegments), Old: aList[i]})
+			default:
+				changes = diffValues(changes, indexSegments, aList[i], bList[i])
+			}
+		}
+		return changes
+	}
+
+	if !equalValues(a, b, EqualOptions{}) {
+		changes = append(changes, Change{Kind: Changed, Path: joinPath(segments), Old: a, New: b})
+	}
+
+	return changes
+}
@@ -0,0 +1,142 @@
+package querymap
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryMapClone(t *testing.T) {
+	m := mustParse(t, "q=shoes&ids=1&ids=2&filters[0][status]=open&page[size]=10", ParseOptions{})
+	clone := m.Clone()
+
+	if !reflect.DeepEqual(clone, m) {
+		t.Fatalf("Clone() = %v, want %v", clone, m)
+	}
+
+	clone["ids"].([]string)[0] = "x"
+	clone["filters"].(anyList)[0].(QueryMap)["status"] = "closed"
+	clone["page"].(QueryMap)["size"] = "20"
+	if !reflect.DeepEqual(m, mustParse(t, "q=shoes&ids=1&ids=2&filters[0][status]=open&page[size]=10", ParseOptions{})) {
+		t.Errorf("Clone() shares values with the original: %v", m)
+	}
+
+	if QueryMap(nil).Clone() != nil {
+		t.Errorf("Clone() of nil is not nil")
+	}
+}
+
+func TestQueryMapEqual(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       QueryMap
+		b       QueryMap
+		options EqualOptions
+		want    bool
+	}{
+		{
+			name: "same",
+			a:    mustParse(t, "a[b]=1&c=2&c=3", ParseOptions{}),
+			b:    mustParse(t, "c=2&c=3&a[b]=1", ParseOptions{}),
+			want: true,
+		},
+		{
+			name: "list types",
+			a:    QueryMap{"a": []string{"x", "y"}},
+			b:    mustParse(t, "a[0]=x&a[1]=y", ParseOptions{}),
+			want: true,
+		},
+		{
+			name: "single value and list",
+			a:    mustParse(t, "a=x&b[c]=y", ParseOptions{}),
+			b:    mustParse(t, "a=x&b[c]=y", ParseOptions{AlwaysSlices: true}),
+			want: false,
+		},
+		{
+			name:    "single value and list ignoring the list kind",
+			a:       mustParse(t, "a=x&b[c]=y&d[0]=z", ParseOptions{}),
+			b:       mustParse(t, "a=x&b[c]=y&d[0]=z", ParseOptions{AlwaysSlices: true}),
+			options: EqualOptions{IgnoreListKind: true},
+			want:    true,
+		},
+		{
+			name: "list order",
+			a:    mustParse(t, "a[]=x&a[]=y", ParseOptions{}),
+			b:    mustParse(t, "a[]=y&a[]=x", ParseOptions{}),
+			want: false,
+		},
+		{
+			name:    "list order ignored",
+			a:       mustParse(t, "a[]=x&a[]=y&a[]=x&b[0][c]=1&b[1][c]=2", ParseOptions{}),
+			b:       mustParse(t, "a[]=x&a[]=x&a[]=y&b[0][c]=2&b[1][c]=1", ParseOptions{}),
+			options: EqualOptions{IgnoreOrder: true},
+			want:    true,
+		},
+		{
+			name:    "list order ignored counts the elements",
+			a:       mustParse(t, "a[]=x&a[]=x&a[]=y", ParseOptions{}),
+			b:       mustParse(t, "a[]=x&a[]=y&a[]=y", ParseOptions{}),
+			options: EqualOptions{IgnoreOrder: true},
+			want:    false,
+		},
+		{
+			name: "missing key",
+			a:    QueryMap{"a": "x", "b": "y"},
+			b:    QueryMap{"a": "x", "c": "y"},
+			want: false,
+		},
+		{
+			name: "map and string",
+			a:    QueryMap{"a": QueryMap{}},
+			b:    QueryMap{"a": ""},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := tt.a.EqualWithOptions(tt.b, tt.options); got != tt.want {
+					t.Errorf("EqualWithOptions(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+				}
+				if got := tt.b.EqualWithOptions(tt.a, tt.options); got != tt.want {
+					t.Errorf("EqualWithOptions(%v, %v) = %v, want %v", tt.b, tt.a, got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestQueryMapDiff(t *testing.T) {
+	a := mustParse(t, "q=shoes&ids=1&ids=2&filters[0][status]=open&filters[1][status]=closed&page[size]=10&sort=name", ParseOptions{})
+	b := mustParse(t, "q=boots&ids=1&ids=2&ids=3&filters[0][status]=open&filters[1][status]=draft&page=1&limit=5", ParseOptions{})
+
+	want := []Change{
+		{Kind: Changed, Path: "filters[1][status]", Old: "closed", New: "draft"},
+		{Kind: Added, Path: "ids[2]", New: "3"},
+		{Kind: Added, Path: "limit", New: "5"},
+		{Kind: Changed, Path: "page", Old: QueryMap{"size": "10"}, New: "1"},
+		{Kind: Changed, Path: "q", Old: "shoes", New: "boots"},
+		{Kind: Removed, Path: "sort", Old: "name"},
+	}
+	got := a.Diff(b)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+
+	if changes := a.Diff(a.Clone()); len(changes) != 0 {
+		t.Errorf("Diff() of a clone = %v, want no changes", changes)
+	}
+
+	wantStrings := []string{
+		"changed 'filters[1][status]': 'closed' => 'draft'",
+		"added 'ids[2]': '3'",
+		"added 'limit': '5'",
+		"changed 'page': 'map[size:10]' => '1'",
+		"changed 'q': 'shoes' => 'boots'",
+		"removed 'sort': 'name'",
+	}
+	for i, change := range got {
+		if change.String() != wantStrings[i] {
+			t.Errorf("Change.String() = %s, want %s", change, wantStrings[i])
+		}
+	}
+}
@@ -0,0 +1,822 @@
+package querymap
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Compat names a reference implementation of nested query parsing reproduced by ParseCompat.
+type Compat int
+
+const (
+	// CompatPHP reproduces PHP parse_str (and $_GET): "a.b" and "a b" become "a_b",
+	// "a[b]c" is read as "a[b]", later values overwrite earlier ones and "a[]" appends to the array.
+	CompatPHP Compat = iota
+
+	// CompatQS reproduces the `qs` package of Node.js with its default options: depth 5,
+	// arrayLimit 20, parameterLimit 1000, repeated keys combined into arrays and sparse arrays compacted.
+	CompatQS
+
+	// CompatRack reproduces Rack::QueryParser (Rails) of Rack 3: "a[]" appends to the array,
+	// "a[][b]" builds an array of hashes, and a type conflict ("a[]=1&a[b]=2") is an error.
+	CompatRack
+)
+
+func (c Compat) String() string {
+	switch c {
+	case CompatPHP:
+		return "php"
+	case CompatQS:
+		return "qs"
+	case CompatRack:
+		return "rack"
+	}
+
+	return "Compat(" + strconv.Itoa(int(c)) + ")"
+}
+
+// CompatError is returned by ParseCompat when the reference implementation rejects the query.
+type CompatError struct {
+	// Compat is the reproduced implementation.
+	Compat Compat
+
+	// Reason is the error message of the reference implementation.
+	Reason string
+}
+
+func (e *CompatError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Compat, e.Reason)
+}
+
+// ParseCompat parses the raw query string ("a[]=1&a[x]=2", without the leading "?")
+// the same way the `compat` implementation does, pair by pair in the original order.
+//
+// Arrays are returned as anyList, and as QueryMap when their keys are not 0..n-1 in order
+// (PHP arrays) or when they have non-numeric keys. The values are strings,
+// except the qs `true` of a value merged into an object ("a[b]=1&a=c") and the Rack nil of a key without "=".
+// Returns *CompatError for the queries rejected by the implementation (only Rack rejects queries).
+func ParseCompat(rawQuery string, compat Compat) (QueryMap, error) {
+	var result *compatMap
+
+	switch compat {
+	case CompatPHP:
+		result = parsePHP(rawQuery)
+	case CompatQS:
+		result = parseQS(rawQuery)
+	case CompatRack:
+		var err error
+		if result, err = parseRack(rawQuery); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, &CompatError{Compat: compat, Reason: "unknown compatibility mode"}
+	}
+
+	return result.toQueryMap(), nil
+}
+
+// compatMap is an ordered map, as PHP arrays, JavaScript objects and Ruby hashes are.
+type compatMap struct {
+	keys   []string
+	values map[string]any
+
+	// php marks a PHP array, converted into a list if its keys are 0..n-1 in order.
+	php bool
+
+	// next is the next free integer key of a PHP array, valid if hasNext.
+	next    int
+	hasNext bool
+}
+
+func newCompatMap() *compatMap {
+	return &compatMap{values: map[string]any{}}
+}
+
+func newPHPArray() *compatMap {
+	return &compatMap{values: map[string]any{}, php: true}
+}
+
+func (m *compatMap) get(key string) (any, bool) {
+	value, ok := m.values[key]
+	return value, ok
+}
+
+// set sets the value by the key, keeping the position of an existing key.
+func (m *compatMap) set(key string, value any) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+
+	if !m.php {
+		return
+	}
+	if index, ok := phpIntKey(key); ok && (!m.hasNext || index >= m.next) {
+		m.next, m.hasNext = index+1, true
+	}
+}
+
+func (m *compatMap) delete(key string) {
+	if _, ok := m.values[key]; !ok {
+		return
+	}
+	delete(m.values, key)
+	m.keys = slices.DeleteFunc(m.keys, func(k string) bool { return k == key })
+}
+
+// toQueryMap converts the map into a QueryMap, see toQueryValue.
+func (m *compatMap) toQueryMap() QueryMap {
+	data := make(QueryMap, len(m.keys))
+	for _, key := range m.keys {
+		data[key] = toQueryValue(m.values[key])
+	}
+
+	return data
+}
+
+// toQueryValue converts the parsed values: arrays and PHP arrays with the keys 0..n-1 in order
+// become anyList, other maps become QueryMap.
+func toQueryValue(v any) any {
+	switch value := v.(type) {
+	case *compatMap:
+		isList := value.php
+		for i, key := range value.keys {
+			if key != strconv.Itoa(i) {
+				isList = false
+				break
+			}
+		}
+		if !isList || len(value.keys) == 0 {
+			return value.toQueryMap()
+		}
+
+		list := make(anyList, len(value.keys))
+		for i, key := range value.keys {
+			list[i] = toQueryValue(value.values[key])
+		}
+		return list
+	case []any:
+		list := make(anyList, 0, len(value))
+		for _, element := range value {
+			if element != jsHole {
+				list = append(list, toQueryValue(element))
+			}
+		}
+		return list
+	}
+
+	return v
+}
+
+// splitPairs splits the raw query into the name and the value of every pair,
+// `hasValue` is false for the pairs without "=".
+func splitPairs(rawQuery string, separator string, visit func(name, value string, hasValue bool)) {
+	for _, pair := range strings.Split(rawQuery, separator) {
+		name, value, hasValue := strings.Cut(pair, "=")
+		visit(name, value, hasValue)
+	}
+}
+
+// phpIntKey reports whether the key is the canonical form of an integer, as PHP converts such keys into integers.
+func phpIntKey(key string) (int, bool) {
+	index, err := strconv.Atoi(key)
+	if err != nil || strconv.Itoa(index) != key || key == "-0" {
+		return 0, false
+	}
+
+	return index, true
+}
+
+// phpMaxNestingLevel is the default `max_input_nesting_level` of PHP.
+const phpMaxNestingLevel = 64
+
+// parsePHP reproduces php_default_treat_data and php_register_variable_ex.
+func parsePHP(rawQuery string) *compatMap {
+	result := newPHPArray()
+
+	splitPairs(
+		rawQuery, "&", func(name, value string, _ bool) {
+			phpRegisterVariable(result, phpURLDecode(name), phpURLDecode(value))
+		},
+	)
+
+	return result
+}
+
+// phpRegisterVariable registers the value by the variable name of the form "a[b][]".
+func phpRegisterVariable(result *compatMap, name, value string) {
+	// Leading spaces are ignored, spaces and dots of the base name become underscores
+	var base strings.Builder
+	name = strings.TrimLeft(name, " ")
+	i := 0
+	for ; i < len(name) && name[i] != '['; i++ {
+		if name[i] == ' ' || name[i] == '.' {
+			base.WriteByte('_')
+		} else {
+			base.WriteByte(name[i])
+		}
+	}
+	if base.Len() == 0 {
+		return
+	}
+
+	current := result
+	index, hasIndex := base.String(), true
+	rest := name[i:]
+
+	for level := 1; rest != ""; level++ {
+		if level > phpMaxNestingLevel {
+			result.delete(base.String())
+			return
+		}
+
+		newIndex, newHasIndex := "", false
+		if !strings.HasPrefix(rest, "[]") {
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				// Not an index: at the first level the "[" becomes "_" and the rest is a part of the name
+				if level == 1 {
+					index = base.String() + "_" + strings.NewReplacer(" ", "_", ".", "_", "[", "_").Replace(rest[1:])
+				}
+				break
+			}
+			newIndex, newHasIndex = rest[1:end], true
+			rest = rest[end+1:]
+		} else {
+			rest = rest[2:]
+		}
+
+		var child *compatMap
+		if !hasIndex {
+			child = newPHPArray()
+			current.phpAppend(child)
+		} else if existing, ok := current.get(index); ok {
+			if child, ok = existing.(*compatMap); !ok {
+				child = newPHPArray()
+				current.set(index, child)
+			}
+		} else {
+			child = newPHPArray()
+			current.set(index, child)
+		}
+
+		current = child
+		index, hasIndex = newIndex, newHasIndex
+
+		// The characters after "]" are ignored unless they start a new "["
+		if !strings.HasPrefix(rest, "[") {
+			break
+		}
+	}
+
+	if hasIndex {
+		current.set(index, value)
+	} else {
+		current.phpAppend(value)
+	}
+}
+
+// phpAppend appends the value to a PHP array with the next free integer key ($a[] = value).
+func (m *compatMap) phpAppend(value any) {
+	next := 0
+	if m.hasNext {
+		next = m.next
+	}
+
+	m.set(strconv.Itoa(next), value)
+}
+
+// phpURLDecode reproduces PHP urldecode: "+" is a space and malformed "%" sequences are kept as is.
+func phpURLDecode(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '+':
+			b.WriteByte(' ')
+		case s[i] == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			value, _ := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			b.WriteByte(byte(value))
+			i += 2
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// The default options of qs.
+const (
+	qsDepth          = 5
+	qsArrayLimit     = 20
+	qsParameterLimit = 1000
+)
+
+// jsHole is a missing element of a sparse JavaScript array.
+var jsHole any = struct{ hole bool }{true}
+
+// qsBracketsPattern matches a "[segment]" of a qs key.
+var qsBracketsPattern = regexp.MustCompile(`\[[^\[\]]*\]`)
+
+// jsObjectPrototypeKeys are the properties of Object.prototype, qs doesn't overwrite them.
+var jsObjectPrototypeKeys = []string{
+	"constructor", "hasOwnProperty", "isPrototypeOf", "propertyIsEnumerable", "toLocaleString", "toString",
+	"valueOf", "__proto__", "__defineGetter__", "__defineSetter__", "__lookupGetter__", "__lookupSetter__",
+}
+
+// parseQS reproduces qs.parse with the default options.
+func parseQS(rawQuery string) *compatMap {
+	rawQuery = strings.NewReplacer("%5B", "[", "%5b", "[", "%5D", "]", "%5d", "]").Replace(rawQuery)
+
+	parts := strings.SplitN(rawQuery, "&", qsParameterLimit+1)
+	if len(parts) > qsParameterLimit {
+		parts = parts[:qsParameterLimit]
+	}
+
+	values := newCompatMap()
+	for _, part := range parts {
+		pos := strings.Index(part, "]=")
+		if pos == -1 {
+			pos = strings.IndexByte(part, '=')
+		} else {
+			pos++
+		}
+
+		var key string
+		var value any = ""
+		if pos == -1 {
+			key = qsDecode(part)
+		} else {
+			key, value = qsDecode(part[:pos]), qsDecode(part[pos+1:])
+		}
+
+		// Repeated keys are combined into an array
+		if existing, ok := values.get(key); ok {
+			value = jsConcat(jsConcat(nil, existing), value)
+		}
+		values.set(key, value)
+	}
+
+	var result any = newCompatMap()
+	for _, key := range values.jsKeys() {
+		if key == "" {
+			continue
+		}
+		value, _ := values.get(key)
+		result = qsMerge(result, qsParseKeys(key, value))
+	}
+
+	return qsCompact(result).(*compatMap)
+}
+
+// qsDecode reproduces the default decoder of qs: "+" is a space, and the string is kept as is
+// if decodeURIComponent fails.
+func qsDecode(s string) string {
+	s = strings.ReplaceAll(s, "+", " ")
+
+	decoded, err := url.PathUnescape(s)
+	if err != nil || !utf8.ValidString(decoded) {
+		return s
+	}
+
+	return decoded
+}
+
+// qsParseKeys splits the key into the parent and at most qsDepth "[segments]" and builds the nested value.
+func qsParseKeys(key string, value any) any {
+	matches := qsBracketsPattern.FindAllStringIndex(key, -1)
+
+	var chain []string
+	parent := key
+	if len(matches) > 0 {
+		parent = key[:matches[0][0]]
+	}
+	if parent != "" {
+		chain = append(chain, parent)
+	}
+
+	for i, match := range matches {
+		if i == qsDepth {
+			chain = append(chain, "["+key[match[0]:]+"]")
+			break
+		}
+		chain = append(chain, key[match[0]:match[1]])
+	}
+
+	leaf := value
+	for i := len(chain) - 1; i >= 0; i-- {
+		root := chain[i]
+		if root == "[]" {
+			leaf = jsConcat(nil, leaf)
+			continue
+		}
+
+		cleanRoot := root
+		if strings.HasPrefix(root, "[") && strings.HasSuffix(root, "]") {
+			cleanRoot = root[1 : len(root)-1]
+		}
+
+		if index, err := strconv.Atoi(cleanRoot); err == nil && root != cleanRoot &&
+			strconv.Itoa(index) == cleanRoot && index >= 0 && index <= qsArrayLimit {
+			array := make([]any, index+1)
+			for j := range array {
+				array[j] = jsHole
+			}
+			array[index] = leaf
+			leaf = array
+			continue
+		}
+
+		object := newCompatMap()
+		if cleanRoot != "__proto__" {
+			object.set(cleanRoot, leaf)
+		}
+		leaf = object
+	}
+
+	return leaf
+}
+
+// jsConcat reproduces [].concat(a, b) for a single `b`: the arrays are flattened by one level.
+func jsConcat(a []any, b any) []any {
+	if array, ok := b.([]any); ok {
+		return append(slices.Clone(a), array...)
+	}
+
+	return append(slices.Clone(a), b)
+}
+
+// jsKeys returns the keys in the order of Object.keys: the array indexes in ascending order,
+// then the other keys in insertion order.
+func (m *compatMap) jsKeys() []string {
+	var indexes, others []string
+	for _, key := range m.keys {
+		if index, err := strconv.ParseUint(key, 10, 32); err == nil && strconv.FormatUint(index, 10) == key &&
+			index < 1<<32-1 {
+			indexes = append(indexes, key)
+		} else {
+			others = append(others, key)
+		}
+	}
+
+	// The canonical indexes are ordered by length first, then lexicographically
+	slices.SortFunc(
+		indexes, func(a, b string) int {
+			if c := cmp.Compare(len(a), len(b)); c != 0 {
+				return c
+			}
+			return strings.Compare(a, b)
+		},
+	)
+
+	return append(indexes, others...)
+}
+
+// jsTruthy reports whether the value is truthy in JavaScript.
+func jsTruthy(v any) bool {
+	switch value := v.(type) {
+	case nil:
+		return false
+	case string:
+		return value != ""
+	case bool:
+		return value
+	}
+
+	return v != jsHole
+}
+
+// isJSObject reports whether the value is a JavaScript object (an object or an array).
+func isJSObject(v any) bool {
+	switch v.(type) {
+	case *compatMap, []any:
+		return true
+	}
+
+	return false
+}
+
+// qsMerge reproduces utils.merge of qs.
+func qsMerge(target, source any) any {
+	if !jsTruthy(source) {
+		return target
+	}
+
+	if !isJSObject(source) {
+		switch t := target.(type) {
+		case []any:
+			return append(t, source)
+		case *compatMap:
+			key := jsString(source)
+			if !slices.Contains(jsObjectPrototypeKeys, key) {
+				t.set(key, true)
+			}
+			return t
+		}
+		return []any{target, source}
+	}
+
+	if !jsTruthy(target) || !isJSObject(target) {
+		return jsConcat([]any{target}, source)
+	}
+
+	targetArray, targetIsArray := target.([]any)
+	sourceArray, sourceIsArray := source.([]any)
+
+	if targetIsArray && sourceIsArray {
+		for i, item := range sourceArray {
+			if item == jsHole {
+				continue
+			}
+			if i < len(targetArray) && targetArray[i] != jsHole {
+				if isJSObject(targetArray[i]) && isJSObject(item) {
+					targetArray[i] = qsMerge(targetArray[i], item)
+				} else {
+					targetArray = append(targetArray, item)
+				}
+				continue
+			}
+			for len(targetArray) <= i {
+				targetArray = append(targetArray, jsHole)
+			}
+			targetArray[i] = item
+		}
+		return targetArray
+	}
+
+	var mergeTarget *compatMap
+	if targetIsArray {
+		mergeTarget = jsArrayToObject(targetArray)
+	} else {
+		mergeTarget = target.(*compatMap)
+	}
+
+	sourceObject, ok := source.(*compatMap)
+	if !ok {
+		sourceObject = jsArrayToObject(sourceArray)
+	}
+
+	for _, key := range sourceObject.jsKeys() {
+		value, _ := sourceObject.get(key)
+		if existing, ok := mergeTarget.get(key); ok {
+			mergeTarget.set(key, qsMerge(existing, value))
+		} else {
+			mergeTarget.set(key, value)
+		}
+	}
+
+	return mergeTarget
+}
+
+// jsArrayToObject reproduces utils.arrayToObject of qs: the elements are keyed by their indexes.
+func jsArrayToObject(array []any) *compatMap {
+	object := newCompatMap()
+	for i, element := range array {
+		if element != jsHole {
+			object.set(strconv.Itoa(i), element)
+		}
+	}
+
+	return object
+}
+
+// jsString converts a primitive into a string as JavaScript does.
+func jsString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	return fmt.Sprint(v)
+}
+
+// qsCompact reproduces utils.compact of qs: the holes of the arrays are removed.
+func qsCompact(v any) any {
+	switch value := v.(type) {
+	case *compatMap:
+		for _, key := range value.keys {
+			value.values[key] = qsCompact(value.values[key])
+		}
+		return value
+	case []any:
+		compacted := make([]any, 0, len(value))
+		for _, element := range value {
+			if element != jsHole {
+				compacted = append(compacted, qsCompact(element))
+			}
+		}
+		return compacted
+	}
+
+	return v
+}
+
+// rackParamDepthLimit is the default param_depth_limit of Rack 3.
+const rackParamDepthLimit = 32
+
+// parseRack reproduces Rack::QueryParser#parse_nested_query.
+func parseRack(rawQuery string) (*compatMap, error) {
+	result := newCompatMap()
+	if rawQuery == "" {
+		return result, nil
+	}
+
+	// The pairs are separated by "&" followed by any number of spaces
+	for _, pair := range strings.Split(rawQuery, "&") {
+		pair = strings.TrimLeft(pair, " ")
+
+		if pair == "" {
+			continue
+		}
+		rawName, rawValue, hasValue := strings.Cut(pair, "=")
+
+		name, err := rackUnescape(rawName)
+		if err != nil {
+			return nil, err
+		}
+
+		var value any
+		if hasValue {
+			if value, err = rackUnescape(rawValue); err != nil {
+				return nil, err
+			}
+		}
+
+		if _, err := rackNormalizeParams(result, name, value, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// rackUnescape reproduces URI.decode_www_form_component.
+func rackUnescape(s string) (string, error) {
+	// A "%" not followed by two hex digits is rejected
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && (i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2])) {
+			return "", &CompatError{Compat: CompatRack, Reason: fmt.Sprintf("invalid %%-encoding (%s)", s)}
+		}
+	}
+
+	decoded, _ := url.QueryUnescape(s)
+	return decoded, nil
+}
+
+// rackNormalizeParams reproduces Rack::QueryParser#_normalize_params.
+func rackNormalizeParams(params *compatMap, name string, value any, depth int) (any, error) {
+	if depth >= rackParamDepthLimit {
+		return nil, &CompatError{Compat: CompatRack, Reason: "exceeded the param depth limit"}
+	}
+
+	var key, after string
+	switch {
+	case name == "":
+		key, after = "", ""
+	case depth == 0:
+		// Start of parsing, "[" at the start of the name is not special
+		if start := strings.IndexByte(name[1:], '['); start != -1 {
+			key, after = name[:start+1], name[start+1:]
+		} else {
+			key, after = name, ""
+		}
+	case strings.HasPrefix(name, "[]"):
+		key, after = "[]", name[2:]
+	case strings.HasPrefix(name, "[") && strings.IndexByte(name[1:], ']') != -1:
+		start := strings.IndexByte(name[1:], ']') + 1
+		key, after = name[1:start], name[start+1:]
+	default:
+		key, after = name, ""
+	}
+
+	if key == "" {
+		return nil, nil
+	}
+
+	switch {
+	case after == "":
+		if key == "[]" && depth != 0 {
+			return []any{value}, nil
+		}
+		params.set(key, value)
+	case after == "[":
+		params.set(name, value)
+	case after == "[]":
+		array, err := rackArray(params, key)
+		if err != nil {
+			return nil, err
+		}
+		params.set(key, append(array, value))
+	case strings.HasPrefix(after, "[]"):
+		// Recognize x[][y] (hash inside array) parameters
+		childKey := ""
+		if len(after) > 3 && after[2] == '[' && strings.HasSuffix(after, "]") {
+			childKey = after[3 : len(after)-1]
+		}
+		if childKey == "" || strings.ContainsAny(childKey, "[]") {
+			childKey = after[2:]
+		}
+
+		array, err := rackArray(params, key)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(array) > 0 {
+			if last, ok := array[len(array)-1].(*compatMap); ok && !rackHasKey(last, childKey) {
+				if _, err := rackNormalizeParams(last, childKey, value, depth+1); err != nil {
+					return nil, err
+				}
+				params.set(key, array)
+				break
+			}
+		}
+
+		child, err := rackNormalizeParams(newCompatMap(), childKey, value, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		params.set(key, append(array, child))
+	default:
+		existing, _ := params.get(key)
+		if existing == nil {
+			existing = newCompatMap()
+		}
+		hash, ok := existing.(*compatMap)
+		if !ok {
+			return nil, rackTypeError("Hash", existing, key)
+		}
+
+		normalized, err := rackNormalizeParams(hash, after, value, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		params.set(key, normalized)
+	}
+
+	return params, nil
+}
+
+// rackArray returns the array by the key (params[k] ||= []), or the Rack type error.
+func rackArray(params *compatMap, key string) ([]any, error) {
+	existing, _ := params.get(key)
+	if existing == nil {
+		return []any{}, nil
+	}
+
+	array, ok := existing.([]any)
+	if !ok {
+		return nil, rackTypeError("Array", existing, key)
+	}
+
+	return array, nil
+}
+
+// rackTypeError reproduces the message of Rack::QueryParser::ParameterTypeError.
+func rackTypeError(expected string, got any, key string) error {
+	var class string
+	switch got.(type) {
+	case string:
+		class = "String"
+	case []any:
+		class = "Array"
+	case *compatMap:
+		class = "Rack::QueryParser::Params"
+	}
+
+	return &CompatError{
+		Compat: CompatRack,
+		Reason: fmt.Sprintf("expected %s (got %s) for param `%s'", expected, class, key),
+	}
+}
+
+// rackHasKey reproduces Rack::QueryParser#params_hash_has_key?.
+func rackHasKey(hash *compatMap, key string) bool {
+	if strings.Contains(key, "[]") {
+		return false
+	}
+
+	var current any = hash
+	for _, part := range strings.FieldsFunc(key, func(r rune) bool { return r == '[' || r == ']' }) {
+		h, ok := current.(*compatMap)
+		if !ok {
+			return false
+		}
+		if current, ok = h.get(part); !ok {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,236 @@
+package querymap
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCompat(t *testing.T) {
+	tests := []struct {
+		name   string
+		compat Compat
+		query  string
+		want   QueryMap
+	}{
+		// PHP, parse_str
+		{
+			name:   "php lists",
+			compat: CompatPHP,
+			query:  "first=value&arr[]=foo+bar&arr[]=baz",
+			want:   QueryMap{"first": "value", "arr": anyList{"foo bar", "baz"}},
+		},
+		{
+			name:   "php dots and spaces in names",
+			compat: CompatPHP,
+			query:  "a.b=1&c+d=2&+e=3",
+			want:   QueryMap{"a_b": "1", "c_d": "2", "e": "3"},
+		},
+		{
+			name:   "php characters after the brackets",
+			compat: CompatPHP,
+			query:  "a[b]c=1",
+			want:   QueryMap{"a": QueryMap{"b": "1"}},
+		},
+		{
+			name:   "php unclosed brackets",
+			compat: CompatPHP,
+			query:  "a[=1&b[c=2&d[e][f=3",
+			want:   QueryMap{"a_": "1", "b_c": "2", "d": QueryMap{"e": "3"}},
+		},
+		{
+			name:   "php mixed keys",
+			compat: CompatPHP,
+			query:  "a[]=1&a[x]=2",
+			want:   QueryMap{"a": QueryMap{"0": "1", "x": "2"}},
+		},
+		{
+			name:   "php append after the highest index",
+			compat: CompatPHP,
+			query:  "a[5]=x&a[]=y",
+			want:   QueryMap{"a": QueryMap{"5": "x", "6": "y"}},
+		},
+		{
+			name:   "php non-canonical index",
+			compat: CompatPHP,
+			query:  "a[01]=x",
+			want:   QueryMap{"a": QueryMap{"01": "x"}},
+		},
+		{
+			name:   "php overwrite",
+			compat: CompatPHP,
+			query:  "a=1&a=2&b=1&b[]=2",
+			want:   QueryMap{"a": "2", "b": anyList{"2"}},
+		},
+		{
+			name:   "php empty base name",
+			compat: CompatPHP,
+			query:  "[a]=1&b=2",
+			want:   QueryMap{"b": "2"},
+		},
+		{
+			name:   "php malformed percent-encoding",
+			compat: CompatPHP,
+			query:  "a=100%&b=%zz",
+			want:   QueryMap{"a": "100%", "b": "%zz"},
+		},
+
+		// qs
+		{
+			name:   "qs lists",
+			compat: CompatQS,
+			query:  "a[]=b&a[]=c",
+			want:   QueryMap{"a": anyList{"b", "c"}},
+		},
+		{
+			name:   "qs indexes out of order",
+			compat: CompatQS,
+			query:  "a[1]=c&a[0]=b",
+			want:   QueryMap{"a": anyList{"b", "c"}},
+		},
+		{
+			name:   "qs sparse arrays are compacted",
+			compat: CompatQS,
+			query:  "a[1]=b&a[15]=c",
+			want:   QueryMap{"a": anyList{"b", "c"}},
+		},
+		{
+			name:   "qs array limit",
+			compat: CompatQS,
+			query:  "a[20]=a&b[21]=b",
+			want:   QueryMap{"a": anyList{"a"}, "b": QueryMap{"21": "b"}},
+		},
+		{
+			name:   "qs depth",
+			compat: CompatQS,
+			query:  "a[b][c][d][e][f][g][h]=i",
+			want: QueryMap{
+				"a": QueryMap{"b": QueryMap{"c": QueryMap{"d": QueryMap{"e": QueryMap{"f": QueryMap{"[g][h]": "i"}}}}}},
+			},
+		},
+		{
+			name:   "qs repeated keys",
+			compat: CompatQS,
+			query:  "a=b&a=c",
+			want:   QueryMap{"a": anyList{"b", "c"}},
+		},
+		{
+			name:   "qs mixed keys",
+			compat: CompatQS,
+			query:  "foo[0]=bar&foo[bad]=baz",
+			want:   QueryMap{"foo": QueryMap{"0": "bar", "bad": "baz"}},
+		},
+		{
+			name:   "qs value merged into an object",
+			compat: CompatQS,
+			query:  "a[b]=c&a=d",
+			want:   QueryMap{"a": QueryMap{"b": "c", "d": true}},
+		},
+		{
+			name:   "qs value merged with an array",
+			compat: CompatQS,
+			query:  "a=b&a[0]=c",
+			want:   QueryMap{"a": anyList{"b", "c"}},
+		},
+		{
+			name:   "qs encoded brackets and special keys",
+			compat: CompatQS,
+			query:  "a%5B%3E%3D%5D=23&foo",
+			want:   QueryMap{"a": QueryMap{">=": "23"}, "foo": ""},
+		},
+
+		// Rack
+		{
+			name:   "rack values",
+			compat: CompatRack,
+			query:  "foo&bar=&baz=1&baz=2",
+			want:   QueryMap{"foo": nil, "bar": "", "baz": "2"},
+		},
+		{
+			name:   "rack empty pairs",
+			compat: CompatRack,
+			query:  "&foo=1&&bar=2",
+			want:   QueryMap{"foo": "1", "bar": "2"},
+		},
+		{
+			name:   "rack lists",
+			compat: CompatRack,
+			query:  "foo[]=bar&foo[]=baz",
+			want:   QueryMap{"foo": anyList{"bar", "baz"}},
+		},
+		{
+			name:   "rack hash inside array",
+			compat: CompatRack,
+			query:  "x[y][][z]=1&x[y][][w]=2",
+			want:   QueryMap{"x": QueryMap{"y": anyList{QueryMap{"z": "1", "w": "2"}}}},
+		},
+		{
+			name:   "rack repeated key starts a new hash",
+			compat: CompatRack,
+			query:  "x[y][][z]=1&x[y][][z]=2",
+			want:   QueryMap{"x": QueryMap{"y": anyList{QueryMap{"z": "1"}, QueryMap{"z": "2"}}}},
+		},
+		{
+			name:   "rack numeric keys stay hashes",
+			compat: CompatRack,
+			query:  "a[0]=b&a[1]=c",
+			want:   QueryMap{"a": QueryMap{"0": "b", "1": "c"}},
+		},
+		{
+			name:   "rack decoding",
+			compat: CompatRack,
+			query:  "my+weird+field=q1%212%22%27w%245%267%2Fz8%29%3F",
+			want:   QueryMap{"my weird field": "q1!2\"'w$5&7/z8)?"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := ParseCompat(tt.query, tt.compat)
+				panicIfErr(err)
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("ParseCompat() = %#v, want %#v", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestParseCompatDifferences(t *testing.T) {
+	const query = "a[]=1&a[x]=2"
+
+	got, err := ParseCompat(query, CompatPHP)
+	panicIfErr(err)
+	if want := (QueryMap{"a": QueryMap{"0": "1", "x": "2"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCompat(php) = %v, want %v", got, want)
+	}
+
+	got, err = ParseCompat(query, CompatQS)
+	panicIfErr(err)
+	if want := (QueryMap{"a": QueryMap{"0": "1", "x": "2"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCompat(qs) = %v, want %v", got, want)
+	}
+
+	const exceptedMessage = "rack: expected Hash (got Array) for param `a'"
+	if _, err = ParseCompat(query, CompatRack); err == nil || err.Error() != exceptedMessage {
+		t.Errorf("Expected error to be '%s', got '%v'", exceptedMessage, err)
+	}
+}
+
+func TestParseCompatErrors(t *testing.T) {
+	tests := []struct {
+		compat Compat
+		query  string
+		want   string
+	}{
+		{compat: CompatRack, query: "x=1&x[y]=1", want: "rack: expected Hash (got String) for param `x'"},
+		{compat: CompatRack, query: "x[y]=1&x[]=1", want: "rack: expected Array (got Rack::QueryParser::Params) for param `x'"},
+		{compat: CompatRack, query: "foo=bar%", want: "rack: invalid %-encoding (bar%)"},
+		{compat: Compat(7), query: "a=1", want: "Compat(7): unknown compatibility mode"},
+	}
+	for _, tt := range tests {
+		_, err := ParseCompat(tt.query, tt.compat)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("ParseCompat(%q, %s) error = '%v', want '%s'", tt.query, tt.compat, err, tt.want)
+		}
+	}
+}
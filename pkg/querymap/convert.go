@@ -0,0 +1,122 @@
+package querymap
+
+import (
+	"encoding"
+	"github.com/mitchellh/mapstructure"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Converter converts a raw query value into a value of a specific Go type.
+// It is created by NewConverter and used by RegisterConverter and DecodeOptions.Converters.
+type Converter struct {
+	typ     reflect.Type
+	convert func(value string) (any, error)
+}
+
+// NewConverter creates a Converter that fills the fields of type T with the result of `convert`.
+// For example, NewConverter(time.ParseDuration) decodes "timeout=5s" into a time.Duration field.
+func NewConverter[T any](convert func(value string) (T, error)) Converter {
+	return Converter{
+		typ: reflect.TypeFor[T](),
+		convert: func(value string) (any, error) {
+			return convert(value)
+		},
+	}
+}
+
+// Type returns the Go type filled by the converter.
+func (c Converter) Type() reflect.Type {
+	return c.typ
+}
+
+// registry holds the converters registered with RegisterConverter.
+var registry = struct {
+	sync.RWMutex
+	converters map[reflect.Type]Converter
+}{
+	converters: map[reflect.Type]Converter{
+		reflect.TypeFor[time.Duration](): NewConverter(parseDuration),
+	},
+}
+
+// RegisterConverter registers the converter for all subsequent decodings,
+// replacing the previously registered converter of the same type.
+// time.Duration is registered by default, the types implementing encoding.TextUnmarshaler
+// (time.Time, net.IP...) are supported without registration.
+func RegisterConverter(converter Converter) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.converters[converter.typ] = converter
+}
+
+// lookupConverter returns the converter of the type `t`:
+// from the per-call `converters` first, then from the global registry.
+func lookupConverter(converters []Converter, t reflect.Type) (Converter, bool) {
+	for _, converter := range converters {
+		if converter.typ == t {
+			return converter, true
+		}
+	}
+
+	registry.RLock()
+	defer registry.RUnlock()
+
+	converter, ok := registry.converters[t]
+	return converter, ok
+}
+
+// textUnmarshalerType is the type of the encoding.TextUnmarshaler interface.
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// newDecodeHook creates a mapstructure hook that converts raw query values (a string,
+// or a []string with a single element) into the target type using the converters,
+// or the UnmarshalText method of the target type.
+func newDecodeHook(converters []Converter) mapstructure.DecodeHookFuncType {
+	return func(from reflect.Type, to reflect.Type, data any) (any, error) {
+		var value string
+		switch raw := data.(type) {
+		case string:
+			value = raw
+		case []string:
+			if len(raw) != 1 {
+				return data, nil
+			}
+			value = raw[0]
+		default:
+			return data, nil
+		}
+
+		if converter, ok := lookupConverter(converters, to); ok {
+			return converter.convert(value)
+		}
+
+		if to.Kind() != reflect.Interface && reflect.PointerTo(to).Implements(textUnmarshalerType) {
+			result := reflect.New(to)
+			if err := result.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+				return nil, err
+			}
+			return result.Elem().Interface(), nil
+		}
+
+		return data, nil
+	}
+}
+
+// parseDuration parses a duration like "5s" or "1h30m",
+// or a number of nanoseconds as written by FromStruct.
+func parseDuration(value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err == nil {
+		return duration, nil
+	}
+
+	if nanoseconds, intErr := strconv.ParseInt(value, 10, 64); intErr == nil {
+		return time.Duration(nanoseconds), nil
+	}
+
+	return 0, err
+}
@@ -0,0 +1,123 @@
+package querymap
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type TestConvertID [4]byte
+
+type TestConvertParams struct {
+	Since    time.Time       `json:"since"`
+	Timeout  time.Duration   `json:"timeout"`
+	IP       net.IP          `json:"ip"`
+	Delays   []time.Duration `json:"delays"`
+	Deadline *time.Time      `json:"deadline"`
+	ID       TestConvertID   `json:"id"`
+}
+
+func parseTestConvertID(value string) (TestConvertID, error) {
+	var id TestConvertID
+	if len(value) != len(id) {
+		return id, errors.New("invalid id length")
+	}
+	copy(id[:], value)
+
+	return id, nil
+}
+
+func TestToStructConverters(t *testing.T) {
+	values, err := url.ParseQuery(
+		"since=2024-01-01T00:00:00Z&timeout=5s&ip=10.0.0.1&delays[]=1s&delays[]=2m&deadline=2024-02-01T12:00:00Z&id=abcd",
+	)
+	panicIfErr(err)
+
+	got, err := ToStructWithOptions[TestConvertParams](
+		FromValues(values), DecodeOptions{Converters: []Converter{NewConverter(parseTestConvertID)}},
+	)
+	panicIfErr(err)
+
+	deadline := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	want := &TestConvertParams{
+		Since:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Timeout:  5 * time.Second,
+		IP:       net.ParseIP("10.0.0.1"),
+		Delays:   []time.Duration{time.Second, 2 * time.Minute},
+		Deadline: &deadline,
+		ID:       TestConvertID{'a', 'b', 'c', 'd'},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ToStructWithOptions() = %+v, want %+v", got, want)
+	}
+}
+
+func TestToStructConverterError(t *testing.T) {
+	values, err := url.ParseQuery("since=yesterday&ip=10.0.0.1")
+	panicIfErr(err)
+
+	_, err = FromValuesToStruct[TestConvertParams](values)
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected *DecodeError, got %v", err)
+	}
+	if len(decodeErr.Errors) != 1 {
+		t.Fatalf("Expected 1 error, got %v", decodeErr.Errors)
+	}
+
+	fieldErr := decodeErr.Errors[0]
+	if fieldErr.Path != "since" || fieldErr.Value != "yesterday" || fieldErr.Type != "time.Time" {
+		t.Errorf("Unexpected field error %+v", fieldErr)
+	}
+	if !strings.HasPrefix(fieldErr.Message, "error decoding 'since': ") {
+		t.Errorf("Unexpected message '%s'", fieldErr.Message)
+	}
+}
+
+func TestRegisterConverter(t *testing.T) {
+	type Params struct {
+		ID TestConvertID `json:"id"`
+	}
+
+	RegisterConverter(NewConverter(parseTestConvertID))
+	t.Cleanup(
+		func() {
+			registry.Lock()
+			defer registry.Unlock()
+			delete(registry.converters, reflect.TypeFor[TestConvertID]())
+		},
+	)
+
+	got, err := FromURLStringToStruct[Params]("http://example.com?id=wxyz")
+	panicIfErr(err)
+
+	if want := (TestConvertID{'w', 'x', 'y', 'z'}); got.ID != want {
+		t.Errorf("Expected ID to be %v, got %v", want, got.ID)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "1h30m", want: 90 * time.Minute},
+		{value: "5000000000", want: 5 * time.Second},
+		{value: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseDuration(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDuration(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("parseDuration(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package querymap
+
+import (
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DecodeOptions configures how ToStructWithOptions converts a QueryMap into a structure.
+// The zero value reproduces the behavior of ToStruct.
+type DecodeOptions struct {
+	// ErrorUnused reports the query parameters that don't match any field of the structure,
+	// so that typos like "pgae=2" are not silently ignored.
+	ErrorUnused bool
+
+	// ErrorUnset reports the fields of the structure (and of the decoded nested structures)
+	// that have no matching query parameter.
+	ErrorUnset bool
+
+	// Converters are used for this call in addition to (and in preference to)
+	// the converters registered with RegisterConverter.
+	Converters []Converter
+}
+
+// ToStructWithOptions converts QueryMap into a structure of type T using mapstructure,
+// configured by `options`. The fields of the structure are named by the `query` tag,
+// then by the `json` tag, then by the Go name. A bracketed name reads a nested key: `query:"page[size]"`.
+// The `query` tag options:
+//   - `default=value` fills the absent parameter (`query:"limit,default=20"`);
+//   - `required` reports the absent parameter as an error (`query:"id,required"`);
+//   - `inline` (or `squash`, `explode`) reads the fields of a nested structure from the parent level;
+//   - `comma`, `pipe`, `space` or `delimiter=x` split the delimited values of a list (`tags=a,b`).
+//
+// Returns *DecodeError pointing to the query parameters that can't be decoded.
+func ToStructWithOptions[T any](m QueryMap, options DecodeOptions) (*T, error) {
+	return toStruct[T](m, options, joinPath)
+}
+
+// toStruct is ToStructWithOptions naming the parameters of the errors by `path`.
+func toStruct[T any](m QueryMap, options DecodeOptions, path pathFunc) (*T, error) {
+	var result T
+
+	config := &mapstructure.DecoderConfig{
+		Result:           &result,
+		WeaklyTypedInput: true,
+		TagName:          "json",
+		ErrorUnset:       options.ErrorUnset,
+		DecodeHook:       newDecodeHook(options.Converters),
+	}
+	m, prepareErrs := prepareQuery(m, reflect.TypeFor[T](), options, path)
+
+	decoder, _ := mapstructure.NewDecoder(config)
+	if err := decoder.Decode(m); err != nil {
+		decodeErr := newDecodeError(m, reflect.TypeFor[T](), err, path)
+		decodeErr.Errors = mergeFieldErrors(prepareErrs, decodeErr.Errors)
+		return nil, decodeErr
+	}
+	if len(prepareErrs) > 0 {
+		return nil, &DecodeError{Errors: prepareErrs}
+	}
+
+	return &result, nil
+}
+
+// ToStructStrict converts QueryMap into a structure of type T,
+// reporting the query parameters that don't match any field as *DecodeError.
+func ToStructStrict[T any](m QueryMap) (*T, error) {
+	return ToStructWithOptions[T](m, DecodeOptions{ErrorUnused: true})
+}
+
+// mergeFieldErrors appends the decoder `errs` to the errors found while preparing the query,
+// skipping the ones (like unset fields) reported for the same path.
+func mergeFieldErrors(prepareErrs []*FieldError, errs []*FieldError) []*FieldError {
+	for _, err := range errs {
+		if !slices.ContainsFunc(prepareErrs, func(prepared *FieldError) bool { return prepared.Path == err.Path }) {
+			prepareErrs = append(prepareErrs, err)
+		}
+	}
+
+	return prepareErrs
+}
+
+// FieldError describes a single query parameter that could not be decoded into the structure.
+type FieldError struct {
+	// Path is the bracket path of the parameter as sent by the client, for example "filters[2][price][min]".
+	Path string
+
+	// Value is the raw value found by the Path (string, []string, QueryMap...), nil if there is none.
+	Value any
+
+	// Type is the Go type expected by the Path, for example "int", empty if it is unknown.
+	Type string
+
+	// Message is the error message, it refers to the parameter by its Path.
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// DecodeError is returned by ToStruct and its wrappers when the QueryMap can't be decoded.
+// It contains an error for every parameter that failed.
+type DecodeError struct {
+	Errors []*FieldError
+}
+
+// Error formats the errors the same way mapstructure does, so that the message stays familiar.
+func (e *DecodeError) Error() string {
+	points := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		points[i] = fmt.Sprintf("* %s", err.Message)
+	}
+
+	sort.Strings(points)
+	return fmt.Sprintf("%d error(s) decoding:\n\n%s", len(e.Errors), strings.Join(points, "\n"))
+}
+
+// Unwrap returns the field errors, so they can be matched with errors.As.
+func (e *DecodeError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+// decodeErrorNamePatterns match the field name in the messages of mapstructure errors,
+// every format is pinned by TestDecodeErrorMessageFormats.
+var decodeErrorNamePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^'([^']*)'`),
+	regexp.MustCompile(`^cannot parse '([^']*)'`),
+	regexp.MustCompile(`^error decoding '([^']*)'`),
+	regexp.MustCompile(`^([^\s']*): `),
+}
+
+// newDecodeError converts the mapstructure error `err` of decoding `m` into a structure of type `t`
+// into *DecodeError, replacing the field names in the messages with query paths named by `path`.
+func newDecodeError(m QueryMap, t reflect.Type, err error, path pathFunc) *DecodeError {
+	var messages []string
+	if mapstructureErr, ok := err.(*mapstructure.Error); ok {
+		messages = mapstructureErr.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+
+	decodeErr := &DecodeError{Errors: make([]*FieldError, 0, len(messages))}
+	for _, message := range messages {
+		if match := unsetFieldsPattern.FindStringSubmatch(message); match != nil {
+			decodeErr.Errors = append(decodeErr.Errors, newUnsetErrors(m, t, match, path)...)
+			continue
+		}
+
+		decodeErr.Errors = append(decodeErr.Errors, newFieldError(m, t, message, path))
+	}
+
+	return decodeErr
+}
+
+// unsetFieldsPattern matches the errors of ErrorUnset, which list all the unset fields of a structure.
+var unsetFieldsPattern = regexp.MustCompile(`^'([^']*)' has unset fields: (.*)$`)
+
+// newUnsetErrors splits an ErrorUnset message into a FieldError per field.
+func newUnsetErrors(m QueryMap, t reflect.Type, match []string, path pathFunc) []*FieldError {
+	parent := splitDecoderName(match[1])
+	keys := strings.Split(match[2], ", ")
+
+	errs := make([]*FieldError, 0, len(keys))
+	for _, key := range keys {
+		segments := append(slices.Clone(parent), key)
+
+		fieldErr := &FieldError{Path: path(querySegments(t, segments)), Value: lookupPath(m, segments)}
+		if fieldType := lookupType(t, segments); fieldType != nil {
+			fieldErr.Type = fieldType.String()
+		}
+		fieldErr.Message = fmt.Sprintf("'%s' is not set", fieldErr.Path)
+
+		errs = append(errs, fieldErr)
+	}
+
+	return errs
+}
+
+// newFieldError creates a FieldError from a single mapstructure error message.
+func newFieldError(m QueryMap, t reflect.Type, message string, path pathFunc) *FieldError {
+	for _, pattern := range decodeErrorNamePatterns {
+		match := pattern.FindStringSubmatchIndex(message)
+		if match == nil {
+			continue
+		}
+
+		segments := splitDecoderName(message[match[2]:match[3]])
+		fieldErr := &FieldError{Path: path(querySegments(t, segments)), Value: lookupPath(m, segments)}
+		if fieldType := lookupType(t, segments); fieldType != nil {
+			fieldErr.Type = fieldType.String()
+		}
+		fieldErr.Message = message[:match[2]] + fieldErr.Path + message[match[3]:]
+
+		return fieldErr
+	}
+
+	return &FieldError{Message: message}
+}
+
+// splitDecoderName splits a mapstructure field name of the form "filters[2].price.min"
+// into the segments "filters", "2", "price", "min".
+func splitDecoderName(name string) []string {
+	var segments []string
+
+	for name != "" {
+		switch name[0] {
+		case '.':
+			name = name[1:]
+		case '[':
+			end := strings.IndexByte(name, ']')
+			if end == -1 {
+				return append(segments, name[1:])
+			}
+			segments = append(segments, name[1:end])
+			name = name[end+1:]
+		default:
+			end := strings.IndexAny(name, ".[")
+			if end == -1 {
+				return append(segments, name)
+			}
+			segments = append(segments, name[:end])
+			name = name[end:]
+		}
+	}
+
+	return segments
+}
+
+// pathFunc returns the bracket path of the query parameter found by the path segments of the query names,
+// see joinPath and Parser.clientPath.
+type pathFunc func(segments []string) string
+
+// joinPath joins the segments into a bracket path: "filters", "2", "price" => "filters[2][price]".
+func joinPath(segments []string) string {
+	path := ""
+	for i, segment := range segments {
+		if i == 0 {
+			path = segment
+			continue
+		}
+		path += "[" + segment + "]"
+	}
+
+	return path
+}
+
+// lookupPath returns the value found by the segments in the QueryMap tree, or nil.
+// The map keys are matched the same way mapstructure matches them: exactly, then case-insensitively.
+func lookupPath(v any, segments []string) any {
+	for _, segment := range segments {
+		switch value := v.(type) {
+		case QueryMap:
+			v = lookupKey(value, segment)
+		case map[string]any:
+			v = lookupKey(value, segment)
+		case anyList:
+			v = lookupIndex(value, segment)
+		case []any:
+			v = lookupIndex(value, segment)
+		case []string:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(value) {
+				return nil
+			}
+			v = value[index]
+		default:
+			return nil
+		}
+	}
+
+	return v
+}
+
+// lookupKey returns the value by the key, matched exactly or case-insensitively.
+func lookupKey[M ~map[string]any](m M, key string) any {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// lookupIndex returns the element by the numeric segment, or nil.
+func lookupIndex[L ~[]any](list L, segment string) any {
+	index, err := strconv.Atoi(segment)
+	if err != nil || index < 0 || index >= len(list) {
+		return nil
+	}
+
+	return list[index]
+}
+
+// lookupType returns the Go type found by the segments in the type `t`, or nil if it is unknown.
+func lookupType(t reflect.Type, segments []string) reflect.Type {
+	for _, segment := range segments {
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == nil {
+			return nil
+		}
+
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := lookupField(t, segment)
+			if !ok {
+				return nil
+			}
+			t = field.Type
+		case reflect.Map, reflect.Slice, reflect.Array:
+			t = t.Elem()
+		default:
+			return nil
+		}
+	}
+
+	return t
+}
+
+// lookupField returns the structure field named `name` by its `json` tag or by its Go name,
+// including the fields of embedded structures tagged with `squash`.
+func lookupField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		fieldName, opts := parseTag(field.Tag.Get("json"))
+		if field.Anonymous && opts.Contains("squash") {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if found, ok := lookupField(embedded, name); ok {
+					return found, true
+				}
+			}
+			continue
+		}
+
+		if fieldName == "" {
+			fieldName = field.Name
+		}
+		if fieldName == name {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
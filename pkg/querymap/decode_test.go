@@ -0,0 +1,295 @@
+package querymap
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+type TestDecodePrice struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+type TestDecodeFilter struct {
+	Name  string           `json:"name"`
+	Price *TestDecodePrice `json:"price"`
+}
+
+type TestDecodeParams struct {
+	Page    int                `json:"page"`
+	Active  bool               `json:"active"`
+	Filters []TestDecodeFilter `json:"filters"`
+	Scores  map[string]float64 `json:"scores"`
+}
+
+func TestToStructDecodeError(t *testing.T) {
+	values, err := url.ParseQuery(
+		"page=two&active=yes&filters[0][name]=a&filters[1][price][min]=cheap&filters[1][price][max]=10&scores[x]=high",
+	)
+	panicIfErr(err)
+
+	_, err = FromValuesToStruct[TestDecodeParams](values)
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected *DecodeError, got %v", err)
+	}
+
+	got := map[string]FieldError{}
+	for _, fieldErr := range decodeErr.Errors {
+		got[fieldErr.Path] = FieldError{Path: fieldErr.Path, Value: fieldErr.Value, Type: fieldErr.Type}
+	}
+
+	want := map[string]FieldError{
+		"page":                   {Path: "page", Value: "two", Type: "int"},
+		"active":                 {Path: "active", Value: "yes", Type: "bool"},
+		"filters[1][price][min]": {Path: "filters[1][price][min]", Value: "cheap", Type: "int"},
+		"scores[x]":              {Path: "scores[x]", Value: "high", Type: "float64"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeError.Errors = %+v, want %+v", got, want)
+	}
+
+	for _, fieldErr := range decodeErr.Errors {
+		if fieldErr.Path == "filters[1][price][min]" {
+			const exceptedMessage = "cannot parse 'filters[1][price][min]' as int: strconv.ParseInt: parsing \"cheap\": invalid syntax"
+			if fieldErr.Message != exceptedMessage {
+				t.Errorf("Expected message to be '%s', got '%s'", exceptedMessage, fieldErr.Message)
+			}
+		}
+	}
+
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) {
+		t.Errorf("Expected error to match *FieldError")
+	}
+}
+
+func TestToStructDecodeErrorClientIndexes(t *testing.T) {
+	values, err := url.ParseQuery("filters[0][status]=open&filters[2][price][min]=abc&filters[7][nmae]=x")
+	panicIfErr(err)
+
+	_, err = FromValuesToStructWithOptions[TestDecodeParams](values, ParseOptions{}, DecodeOptions{ErrorUnused: true})
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected *DecodeError, got %v", err)
+	}
+
+	const exceptedMessage = "3 error(s) decoding:\n\n" +
+		"* 'filters[0][status]' is an unknown parameter\n" +
+		"* 'filters[7][nmae]' is an unknown parameter\n" +
+		"* cannot parse 'filters[2][price][min]' as int: strconv.ParseInt: parsing \"abc\": invalid syntax"
+	if err.Error() != exceptedMessage {
+		t.Errorf("Expected error message to be '%s', got '%s'", exceptedMessage, err.Error())
+	}
+
+	got := map[string]any{}
+	for _, fieldErr := range decodeErr.Errors {
+		got[fieldErr.Path] = fieldErr.Value
+	}
+	want := map[string]any{"filters[0][status]": "open", "filters[2][price][min]": "abc", "filters[7][nmae]": "x"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeError.Errors = %v, want %v", got, want)
+	}
+
+	// The parsed QueryMap no longer knows the indexes of the client
+	_, err = ToStruct[TestDecodeParams](FromValues(values))
+	if !errors.As(err, &decodeErr) || decodeErr.Errors[0].Path != "filters[1][price][min]" {
+		t.Errorf("Expected the path of ToStruct to be 'filters[1][price][min]', got %v", err)
+	}
+}
+
+func TestClientSegments(t *testing.T) {
+	raw := QueryMap{
+		"0": "top",
+		"a": QueryMap{"3": QueryMap{"b": QueryMap{"10": "x", "2": "y"}}, "8": "z"},
+		"c": []string{"x", "y"},
+	}
+
+	tests := []struct {
+		segments []string
+		want     []string
+	}{
+		{segments: []string{"0"}, want: []string{"0"}},
+		{segments: []string{"a", "0", "b", "1"}, want: []string{"a", "3", "b", "10"}},
+		{segments: []string{"a", "1"}, want: []string{"a", "8"}},
+		{segments: []string{"a", "2", "b"}, want: []string{"a", "2", "b"}},
+		{segments: []string{"c", "1"}, want: []string{"c", "1"}},
+		{segments: []string{"d", "0"}, want: []string{"d", "0"}},
+	}
+	for _, tt := range tests {
+		if got := clientSegments(raw, tt.segments); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("clientSegments(%v) = %v, want %v", tt.segments, got, tt.want)
+		}
+	}
+}
+
+func TestSplitDecoderName(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "", want: nil},
+		{name: "page", want: []string{"page"}},
+		{name: "filters[2].price.min", want: []string{"filters", "2", "price", "min"}},
+		{name: "scores[x][0]", want: []string{"scores", "x", "0"}},
+	}
+	for _, tt := range tests {
+		if got := splitDecoderName(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitDecoderName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestToStructStrict(t *testing.T) {
+	values, err := url.ParseQuery("pgae=2&filters[0][name]=a&filters[0][nmae]=b&active=true")
+	panicIfErr(err)
+
+	m := FromValues(values)
+
+	if _, err := ToStruct[TestDecodeParams](m); err != nil {
+		t.Fatalf("Expected ToStruct to ignore unknown keys, got %v", err)
+	}
+
+	_, err = ToStructStrict[TestDecodeParams](m)
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected *DecodeError, got %v", err)
+	}
+
+	const exceptedMessage = "2 error(s) decoding:\n\n* 'filters[0][nmae]' is an unknown parameter\n* 'pgae' is an unknown parameter"
+	if err.Error() != exceptedMessage {
+		t.Errorf("Expected error message to be '%s', got '%s'", exceptedMessage, err.Error())
+	}
+
+	got := map[string]any{}
+	for _, fieldErr := range decodeErr.Errors {
+		got[fieldErr.Path] = fieldErr.Value
+	}
+	want := map[string]any{"pgae": "2", "filters[0][nmae]": "b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeError.Errors = %v, want %v", got, want)
+	}
+}
+
+func TestToStructWithOptionsErrorUnset(t *testing.T) {
+	values, err := url.ParseQuery("page=2&filters[0][name]=a")
+	panicIfErr(err)
+
+	_, err = ToStructWithOptions[TestDecodeParams](FromValues(values), DecodeOptions{ErrorUnset: true})
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected *DecodeError, got %v", err)
+	}
+
+	got := map[string]string{}
+	for _, fieldErr := range decodeErr.Errors {
+		got[fieldErr.Path] = fieldErr.Type
+	}
+	want := map[string]string{
+		"active":            "bool",
+		"scores":            "map[string]float64",
+		"filters[0][price]": "*querymap.TestDecodePrice",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeError.Errors = %v, want %v", got, want)
+	}
+}
+
+type TestDecodeFormats struct {
+	Items []struct {
+		Count  int              `json:"count"`
+		Ratio  float64          `json:"ratio"`
+		Name   string           `json:"name"`
+		Price  TestDecodePrice  `json:"price"`
+		Code   TestDecodeCode   `json:"code"`
+		Notify chan int         `json:"notify"`
+		Scores map[string]int   `json:"scores"`
+		Unset  *TestDecodePrice `json:"unset"`
+	} `json:"items"`
+}
+
+type TestDecodeCode string
+
+// The field names are recovered from the messages of mapstructure, so every format parsed
+// by decodeErrorNamePatterns and unsetFieldsPattern is pinned here against the real decoder.
+func TestDecodeErrorMessageFormats(t *testing.T) {
+	converter := NewConverter(
+		func(value string) (TestDecodeCode, error) {
+			return "", errors.New("unknown code")
+		},
+	)
+
+	tests := []struct {
+		query string
+		path  string
+		want  string
+	}{
+		{
+			query: "items[5][count]=x",
+			path:  "items[5][count]",
+			want:  "cannot parse 'items[5][count]' as int: strconv.ParseInt: parsing \"x\": invalid syntax",
+		},
+		{
+			query: "items[5][ratio]=x",
+			path:  "items[5][ratio]",
+			want:  "cannot parse 'items[5][ratio]' as float: strconv.ParseFloat: parsing \"x\": invalid syntax",
+		},
+		{
+			query: "items[5][name][a]=x",
+			path:  "items[5][name]",
+			want:  "'items[5][name]' expected type 'string', got unconvertible type 'querymap.QueryMap', value: 'map[a:x]'",
+		},
+		{
+			query: "items[5][scores]=x",
+			path:  "items[5][scores]",
+			want:  "'items[5][scores]' expected a map, got 'string'",
+		},
+		{
+			query: "items[5][code]=x",
+			path:  "items[5][code]",
+			want:  "error decoding 'items[5][code]': unknown code",
+		},
+		{
+			query: "items[5][notify]=x",
+			path:  "items[5][notify]",
+			want:  "items[5][notify]: unsupported type: chan",
+		},
+		{
+			query: "items[5][unset][min]=1",
+			path:  "items[5][unset][max]",
+			want:  "'items[5][unset][max]' is not set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.query, func(t *testing.T) {
+				values, err := url.ParseQuery(tt.query)
+				panicIfErr(err)
+
+				_, err = FromValuesToStructWithOptions[TestDecodeFormats](
+					values, ParseOptions{}, DecodeOptions{ErrorUnset: true, Converters: []Converter{converter}},
+				)
+
+				var decodeErr *DecodeError
+				if !errors.As(err, &decodeErr) {
+					t.Fatalf("Expected *DecodeError, got %v", err)
+				}
+				for _, fieldErr := range decodeErr.Errors {
+					if fieldErr.Path == tt.path {
+						if fieldErr.Message != tt.want {
+							t.Errorf("Expected message to be '%s', got '%s'", tt.want, fieldErr.Message)
+						}
+						return
+					}
+				}
+				t.Errorf("Expected an error for '%s', got %v", tt.path, err)
+			},
+		)
+	}
+}
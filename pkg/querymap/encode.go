@@ -0,0 +1,110 @@
+package querymap
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ToValues flattens the QueryMap back into url.Values using the bracket notation,
+// so the result can be parsed again by FromValues.
+// For example, QueryMap{"a": QueryMap{"b": []string{"1", "2"}}} => a[b][]=1&a[b][]=2.
+//
+// The round trip is lossy in two cases: keys are written without escaping, so a key
+// containing "[" or "]" is split differently when parsed again, and an empty []string,
+// anyList or QueryMap has no representation in a query string and is omitted.
+func (q QueryMap) ToValues() url.Values {
+	return q.ToValuesWithOptions(EncodeOptions{})
+}
+
+// EncodeOptions configures how a QueryMap is flattened into query parameters.
+// The zero value reproduces the behavior of ToValues.
+type EncodeOptions struct {
+	// Syntax selects the notation of the nested keys:
+	// BracketSyntax writes "a[b][c]" (default), DotSyntax writes "a.b.c" (lists are still indexed as "a[0].b").
+	// With DotSyntax and FlatSyntax a []string is written as a repeated key instead of "key[]",
+	// so a []string with a single element is parsed back as a string.
+	Syntax Syntax
+}
+
+// ToValuesWithOptions flattens the QueryMap into url.Values using the notation selected by `options`,
+// so the result can be parsed again by a Parser with the same Syntax.
+func (q QueryMap) ToValuesWithOptions(options EncodeOptions) url.Values {
+	values := make(url.Values)
+	e := encoder{options: options}
+
+	for key, value := range q {
+		if options.Syntax == DotSyntax {
+			key = escapeDots(key)
+		}
+		e.encodeValue(values, key, value)
+	}
+
+	return values
+}
+
+// Encode flattens the QueryMap with ToValues and encodes the result
+// into the "URL encoded" form ("a%5Bb%5D=1&c=2") sorted by key.
+func (q QueryMap) Encode() string {
+	return q.ToValues().Encode()
+}
+
+// EncodeWithOptions flattens the QueryMap with ToValuesWithOptions and encodes the result
+// into the "URL encoded" form sorted by key.
+func (q QueryMap) EncodeWithOptions(options EncodeOptions) string {
+	return q.ToValuesWithOptions(options).Encode()
+}
+
+// encoder flattens QueryMap values according to its EncodeOptions.
+type encoder struct {
+	options EncodeOptions
+}
+
+// childKey returns the key of the map entry `name` under the `key` prefix.
+func (e encoder) childKey(key, name string) string {
+	if e.options.Syntax == DotSyntax {
+		return key + "." + escapeDots(name)
+	}
+
+	return key + "[" + name + "]"
+}
+
+// escapeDots escapes the dots of a DotSyntax key segment: "file.name" => `file\.name`.
+func escapeDots(name string) string {
+	return strings.ReplaceAll(name, ".", `\.`)
+}
+
+// encodeValue - recursively writes `untypedValue` into `values` under the `key` prefix.
+// Lists of strings are written as "key[]", other lists as "key[0]", "key[1]"... and maps as "key[name]"
+// (or "key.name" in DotSyntax).
+func (e encoder) encodeValue(values url.Values, key string, untypedValue any) {
+	switch value := untypedValue.(type) {
+	case nil:
+		return
+	case string:
+		values.Add(key, value)
+	case []string:
+		if len(value) == 0 {
+			return
+		}
+		if e.options.Syntax == BracketSyntax {
+			key += "[]"
+		}
+		values[key] = append(values[key], value...)
+	case anyList:
+		for i, v := range value {
+			e.encodeValue(values, key+"["+strconv.Itoa(i)+"]", v)
+		}
+	case []any:
+		e.encodeValue(values, key, anyList(value))
+	case QueryMap:
+		for k, v := range value {
+			e.encodeValue(values, e.childKey(key, k), v)
+		}
+	case map[string]any:
+		e.encodeValue(values, key, QueryMap(value))
+	default:
+		values.Add(key, fmt.Sprint(value))
+	}
+}
@@ -0,0 +1,159 @@
+package querymap
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestQueryMapToValues(t *testing.T) {
+	tests := []struct {
+		name string
+		qm   QueryMap
+		want url.Values
+	}{
+		{
+			name: "simple",
+			qm:   QueryMap{"b": "1", "c": "2"},
+			want: url.Values{"b": {"1"}, "c": {"2"}},
+		},
+		{
+			name: "empty",
+			qm:   QueryMap{},
+			want: url.Values{},
+		},
+		{
+			name: "string slice",
+			qm:   QueryMap{"b": []string{"1", "2"}},
+			want: url.Values{"b[]": {"1", "2"}},
+		},
+		{
+			name: "any list",
+			qm:   QueryMap{"b": anyList{"1", "2"}},
+			want: url.Values{"b[0]": {"1"}, "b[1]": {"2"}},
+		},
+		{
+			name: "nested",
+			qm:   QueryMap{"a": QueryMap{"b": QueryMap{"c": "1", "d": "2"}}},
+			want: url.Values{"a[b][c]": {"1"}, "a[b][d]": {"2"}},
+		},
+		{
+			name: "array with object",
+			qm:   QueryMap{"b": anyList{QueryMap{"c": "1"}, QueryMap{"c": "2"}}},
+			want: url.Values{"b[0][c]": {"1"}, "b[1][c]": {"2"}},
+		},
+		{
+			name: "nested arrays",
+			qm:   QueryMap{"b": anyList{[]string{"1", "2"}, []string{"3"}}},
+			want: url.Values{"b[0][]": {"1", "2"}, "b[1][]": {"3"}},
+		},
+		{
+			name: "empty containers are omitted",
+			qm:   QueryMap{"a": []string{}, "b": anyList{}, "c": QueryMap{}, "d": "1"},
+			want: url.Values{"d": {"1"}},
+		},
+		{
+			name: "plain go types",
+			qm:   QueryMap{"a": map[string]any{"b": []any{"1", 2}}, "c": true, "d": nil},
+			want: url.Values{"a[b][0]": {"1"}, "a[b][1]": {"2"}, "c": {"true"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := tt.qm.ToValues(); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("ToValues() = %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestQueryMapEncodeRoundTrip(t *testing.T) {
+	tests := []QueryMap{
+		{"b": "1", "c": "2"},
+		{"b": []string{"1", "2"}},
+		{"b": anyList{"", "2"}},
+		{"b": "hello world", "key!": "hello, world!"},
+		{"a": QueryMap{"b": "1", "c": QueryMap{"d": QueryMap{"e": "2"}}}},
+		{"b": anyList{QueryMap{"c": "1", "d": "2"}}},
+		{"b": anyList{[]string{"1", "2"}, []string{"3"}}},
+		{"filter": QueryMap{"name": []string{"Ken", "Ken2"}}, "pagination": QueryMap{"limit": "25"}},
+	}
+	for _, qm := range tests {
+		encoded := qm.Encode()
+
+		values, err := url.ParseQuery(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := FromValues(values); !reflect.DeepEqual(got, qm) {
+			t.Errorf("FromValues(%q) = %v, want %v", encoded, got, qm)
+		}
+	}
+}
+
+func TestQueryMapEncode(t *testing.T) {
+	qm := QueryMap{"a": QueryMap{"b": "1"}, "c": []string{"x y"}}
+
+	const want = "a%5Bb%5D=1&c%5B%5D=x+y"
+	if got := qm.Encode(); got != want {
+		t.Errorf("Encode() = %v, want %v", got, want)
+	}
+}
+
+func TestQueryMapToValuesWithOptions(t *testing.T) {
+	qm := QueryMap{
+		"filter":    QueryMap{"status": "open", "owner": QueryMap{"id": "7"}, "tags": []string{"a", "b"}},
+		"items":     anyList{QueryMap{"name": "x"}, "y"},
+		"file.name": QueryMap{"v.1": "a"},
+	}
+
+	tests := []struct {
+		name    string
+		options EncodeOptions
+		want    url.Values
+	}{
+		{
+			name:    "bracket syntax",
+			options: EncodeOptions{},
+			want: url.Values{
+				"filter[status]":    {"open"},
+				"filter[owner][id]": {"7"},
+				"filter[tags][]":    {"a", "b"},
+				"items[0][name]":    {"x"},
+				"items[1]":          {"y"},
+				"file.name[v.1]":    {"a"},
+			},
+		},
+		{
+			name:    "dot syntax",
+			options: EncodeOptions{Syntax: DotSyntax},
+			want: url.Values{
+				"filter.status":   {"open"},
+				"filter.owner.id": {"7"},
+				"filter.tags":     {"a", "b"},
+				"items[0].name":   {"x"},
+				"items[1]":        {"y"},
+				`file\.name.v\.1`: {"a"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got := qm.ToValuesWithOptions(tt.options)
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("ToValuesWithOptions() = %v, want %v", got, tt.want)
+				}
+
+				parsed, err := FromValuesWithOptions(got, ParseOptions{Syntax: tt.options.Syntax})
+				panicIfErr(err)
+				if !reflect.DeepEqual(parsed, qm) {
+					t.Errorf("FromValuesWithOptions() = %v, want %v", parsed, qm)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,338 @@
+package querymap
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FromStruct converts a structure of type T into a QueryMap, the reverse of ToStruct.
+// The fields of the structure are named the same way ToStruct reads them (by the `query` tag,
+// then by the `json` tag), fields tagged with `omitempty` are skipped when empty
+// and fields tagged with `-` are always skipped.
+func FromStruct[T any](value T) (QueryMap, error) {
+	untypedData, err := fromReflectValue("", reflect.ValueOf(value))
+	if err != nil {
+		return nil, err
+	}
+
+	switch data := untypedData.(type) {
+	case nil:
+		return newQueryMap(), nil
+	case QueryMap:
+		return data, nil
+	}
+
+	return nil, encodeError("", "expected a struct or a map, got %T", value)
+}
+
+// StructToValues is a convenient function that combines FromStruct and QueryMap.ToValues.
+// Accepts a structure of type T and returns its fields as bracket-notation url.Values.
+func StructToValues[T any](value T) (url.Values, error) {
+	data, err := FromStruct(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.ToValues(), nil
+}
+
+// fromReflectValue - recursively converts the value into one of the QueryMap value types
+// (string, []string, anyList, QueryMap). Returns nil for values that should be omitted.
+// The `name` is the bracket path of the value, it is used in error messages only.
+func fromReflectValue(name string, value reflect.Value) (any, error) {
+	if !value.IsValid() {
+		return nil, nil
+	}
+
+	if marshaler, ok := textMarshaler(value); ok {
+		text, err := marshaler.MarshalText()
+		if err != nil {
+			return nil, encodeError(name, "error encoding text: %w", err)
+		}
+		return string(text), nil
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil, nil
+		}
+		return fromReflectValue(name, value.Elem())
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, value.Type().Bits()), nil
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil, nil
+		}
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 { // []byte is decoded back from a string
+			bytes := make([]byte, value.Len())
+			for i := range bytes {
+				bytes[i] = byte(value.Index(i).Uint())
+			}
+			return string(bytes), nil
+		}
+		return fromReflectList(name, value)
+	case reflect.Map:
+		if value.IsNil() {
+			return nil, nil
+		}
+		return fromReflectMap(name, value)
+	case reflect.Struct:
+		data := newQueryMap()
+		if err := fromReflectStruct(name, value, data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	return nil, encodeError(name, "unsupported type: %s", value.Kind())
+}
+
+// fromReflectList converts a slice or an array into []string if all of its elements
+// are strings, otherwise into anyList. Nil elements are rejected, because the parser
+// compacts the indexes and every following element would shift into their place.
+func fromReflectList(name string, value reflect.Value) (any, error) {
+	if value.Len() == 0 {
+		return nil, nil
+	}
+
+	entry := make(anyList, value.Len())
+	allStrings := true
+
+	for i := range value.Len() {
+		elementName := name + "[" + strconv.Itoa(i) + "]"
+		v, err := fromReflectValue(elementName, value.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			return nil, encodeError(elementName, "unsupported nil element")
+		}
+		if _, ok := v.(string); !ok {
+			allStrings = false
+		}
+		entry[i] = v
+	}
+
+	if !allStrings {
+		return entry, nil
+	}
+
+	strs := make([]string, len(entry))
+	for i, v := range entry {
+		strs[i] = v.(string)
+	}
+
+	return strs, nil
+}
+
+// fromReflectMap converts a map into QueryMap, the keys are formatted as strings.
+func fromReflectMap(name string, value reflect.Value) (any, error) {
+	data := newQueryMap()
+
+	iter := value.MapRange()
+	for iter.Next() {
+		key, err := fromReflectValue(name, iter.Key())
+		if err != nil {
+			return nil, err
+		}
+
+		keyString, ok := key.(string)
+		if !ok {
+			return nil, encodeError(name, "unsupported map key type: %s", iter.Key().Type())
+		}
+
+		v, err := fromReflectValue(name+"["+keyString+"]", iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			data[keyString] = v
+		}
+	}
+
+	return data, nil
+}
+
+// fromReflectStruct writes the exported fields of the structure into `data`.
+// Inline structures (`query:",inline"`, `json:",squash"`...) are written into the same `data`.
+func fromReflectStruct(name string, value reflect.Value, data QueryMap) error {
+	for _, f := range structFields(value.Type()) {
+		fieldValue := value.FieldByIndex(f.field.Index)
+		if f.opts.Contains("omitempty") && isEmptyValue(fieldValue) {
+			continue
+		}
+
+		if f.inline {
+			for fieldValue.Kind() == reflect.Pointer {
+				if fieldValue.IsNil() {
+					break
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			if fieldValue.Kind() == reflect.Struct {
+				if err := fromReflectStruct(name, fieldValue, data); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		fieldPath := name
+		for _, segment := range f.path {
+			fieldPath = joinKey(fieldPath, segment)
+		}
+
+		v, err := fromReflectValue(fieldPath, fieldValue)
+		if err != nil {
+			return err
+		}
+		if delimiter, ok := tagDelimiter(f.opts); ok {
+			v = joinDelimited(v, delimiter)
+		}
+		if v != nil {
+			setFieldValue(data, f.path, v)
+		}
+	}
+
+	return nil
+}
+
+// setFieldValue sets the value by the path segments of a field name,
+// creating the nested maps of the bracketed names: "page[size]" => QueryMap{"page": QueryMap{"size": v}}.
+func setFieldValue(data QueryMap, segments []string, v any) {
+	last := len(segments) - 1
+	for _, segment := range segments[:last] {
+		child, ok := data[segment].(QueryMap)
+		if !ok {
+			child = newQueryMap()
+			data[segment] = child
+		}
+		data = child
+	}
+
+	data[segments[last]] = v
+}
+
+// joinDelimited joins the elements of a list of scalars with the delimiter,
+// the reverse of the `comma`, `pipe`, `space` and `delimiter=x` tag options. Other values are returned as is.
+func joinDelimited(v any, delimiter string) any {
+	switch value := v.(type) {
+	case []string:
+		if len(value) == 0 {
+			return nil
+		}
+		return strings.Join(value, delimiter)
+	case anyList:
+		elements := make([]string, len(value))
+		for i, element := range value {
+			s, ok := element.(string)
+			if !ok {
+				return v
+			}
+			elements[i] = s
+		}
+		return joinDelimited(elements, delimiter)
+	}
+
+	return v
+}
+
+// encodeError formats an error of FromStruct, prefixed by the bracket path `name` if it's known.
+func encodeError(name string, format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	if name == "" {
+		return err
+	}
+
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// isEmptyValue reports whether the value is empty in terms of the `omitempty` option:
+// false, 0, a nil pointer, a nil interface value, and any empty array, slice, map, or string.
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return value.IsZero()
+	}
+	return false
+}
+
+// textMarshaler returns the encoding.TextMarshaler implementation of the value, if any.
+// The interfaces are unwrapped first, so that a typed nil pointer they hold is not marshaled.
+func textMarshaler(value reflect.Value) (encoding.TextMarshaler, bool) {
+	for value.Kind() == reflect.Interface && !value.IsNil() {
+		value = value.Elem()
+	}
+	if (value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface) && value.IsNil() {
+		return nil, false
+	}
+	if !value.CanInterface() {
+		return nil, false
+	}
+
+	if marshaler, ok := value.Interface().(encoding.TextMarshaler); ok {
+		return marshaler, true
+	}
+	if value.CanAddr() {
+		if marshaler, ok := value.Addr().Interface().(encoding.TextMarshaler); ok {
+			return marshaler, true
+		}
+	}
+
+	return nil, false
+}
+
+// tagOptions is the string following a comma in a struct field's tag,
+// or the empty string. It does not include the leading comma.
+type tagOptions string
+
+// parseTag splits a struct field's tag into its name and comma-separated options.
+func parseTag(tag string) (string, tagOptions) {
+	name, opts, _ := strings.Cut(tag, ",")
+	return name, tagOptions(opts)
+}
+
+// Contains reports whether a comma-separated list of options contains a particular option.
+func (o tagOptions) Contains(option string) bool {
+	s := string(o)
+	for s != "" {
+		var name string
+		name, s, _ = strings.Cut(s, ",")
+		if name == option {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup returns the value of an option of the form "name=value" from a comma-separated list of options.
+func (o tagOptions) Lookup(option string) (string, bool) {
+	s := string(o)
+	for s != "" {
+		var name string
+		name, s, _ = strings.Cut(s, ",")
+		if value, ok := strings.CutPrefix(name, option+"="); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
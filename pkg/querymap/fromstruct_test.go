@@ -0,0 +1,172 @@
+package querymap
+
+import (
+	"net"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFromStruct(t *testing.T) {
+	title := "test"
+	value := TestStruct4{
+		Title: &title,
+		Names: []*TestStruct1{{Name: "123"}},
+		Locations1: map[string][]*TestStruct2{
+			"home": {{Longitude: 1.5, Latitude: 2.25}},
+		},
+		View:      &TestStruct3{Height: 3.14, Width: 3.14},
+		Immutable: TestStruct3{Width: 1},
+		Count:     100,
+	}
+
+	want := QueryMap{
+		"title": "test",
+		"names": anyList{QueryMap{"name": "123"}},
+		"locations_1": QueryMap{
+			"home": anyList{QueryMap{"longitude": "1.5", "latitude": "2.25"}},
+		},
+		"view":      QueryMap{"height": "3.14", "width": "3.14"},
+		"immutable": QueryMap{"width": "1"},
+		"count":     "100",
+	}
+
+	got, err := FromStruct(value)
+	panicIfErr(err)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FromStruct() = %v, want %v", got, want)
+	}
+
+	gotFromPointer, err := FromStruct(&value)
+	panicIfErr(err)
+	if !reflect.DeepEqual(gotFromPointer, want) {
+		t.Errorf("FromStruct() = %v, want %v", gotFromPointer, want)
+	}
+}
+
+func TestStructToValuesRoundTrip(t *testing.T) {
+	title := "test"
+	value := TestStruct4{
+		Title: &title,
+		Names: []*TestStruct1{{Name: "123"}, {Name: "456"}},
+		Locations1: map[string][]*TestStruct2{
+			"home": {{Longitude: 1.5, Latitude: 2.25}},
+			"work": {{Longitude: 3}, {Latitude: 4}},
+		},
+		Views:     []*TestStruct3{{Height: 3.14, Width: 3.14}},
+		Immutable: TestStruct3{Height: 1, Width: 2},
+		Count:     100,
+	}
+
+	values, err := StructToValues(value)
+	panicIfErr(err)
+
+	got, err := FromValuesToStruct[TestStruct4](values)
+	panicIfErr(err)
+	if !reflect.DeepEqual(*got, value) {
+		t.Errorf("FromValuesToStruct(StructToValues()) = %+v, want %+v", *got, value)
+	}
+}
+
+func TestStructToValues(t *testing.T) {
+	type Embedded struct {
+		Page int `json:"page"`
+	}
+	type Params struct {
+		Embedded `json:",squash"`
+		Tags     []string `json:"tags"`
+		IDs      []int    `json:"ids,omitempty"`
+		Skipped  string   `json:"-"`
+		Empty    string   `json:"empty,omitempty"`
+		IP       net.IP   `json:"ip"`
+		Untagged bool
+		hidden   string
+	}
+
+	values, err := StructToValues(
+		Params{
+			Embedded: Embedded{Page: 2},
+			Tags:     []string{"a", "b"},
+			Skipped:  "x",
+			IP:       net.IPv4(127, 0, 0, 1),
+			Untagged: true,
+			hidden:   "y",
+		},
+	)
+	panicIfErr(err)
+
+	want := url.Values{
+		"page":     {"2"},
+		"tags[]":   {"a", "b"},
+		"ip":       {"127.0.0.1"},
+		"Untagged": {"true"},
+	}
+
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("StructToValues() = %v, want %v", values, want)
+	}
+}
+
+func TestFromStructError(t *testing.T) {
+	_, err := FromStruct(struct {
+		Nested struct {
+			Value complex64 `json:"value"`
+		} `json:"nested"`
+	}{})
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	} else {
+		const exceptedErr = "nested[value]: unsupported type: complex64"
+		if err.Error() != exceptedErr {
+			t.Errorf("Expected error to be '%s', got %v", exceptedErr, err)
+		}
+	}
+
+	if _, err = FromStruct("string"); err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+
+	type Element struct {
+		Name string `json:"name"`
+	}
+	_, err = FromStruct(struct {
+		List []*Element `json:"list"`
+	}{List: []*Element{nil, {Name: "x"}}})
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	} else {
+		const exceptedErr = "list[0]: unsupported nil element"
+		if err.Error() != exceptedErr {
+			t.Errorf("Expected error to be '%s', got %v", exceptedErr, err)
+		}
+	}
+}
+
+func TestFromStructDashTag(t *testing.T) {
+	got, err := FromStruct(struct {
+		Skipped string `json:"-"`
+		Dash    string `json:"-,"`
+	}{Skipped: "a", Dash: "b"})
+	panicIfErr(err)
+
+	want := QueryMap{"-": "b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FromStruct() = %v, want %v", got, want)
+	}
+}
+
+func TestFromStructInterfaceNilPointer(t *testing.T) {
+	// A nil *time.Time in an interface used to call time.Time.MarshalText on the nil pointer
+	got, err := FromStruct(struct {
+		Any   any `json:"any"`
+		Empty any `json:"empty"`
+		Time  any `json:"time"`
+	}{Any: (*time.Time)(nil), Time: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)})
+	panicIfErr(err)
+
+	want := QueryMap{"time": "2024-05-01T00:00:00Z"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FromStruct() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+package querymap
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// fuzzQueries are the seed corpus of the fuzz targets: well-formed and malformed bracket keys,
+// numeric indexes, percent-encoding and separators.
+var fuzzQueries = []string{
+	"",
+	"a=1&b=2&b=3",
+	"a[b][c]=1&a[b][d]=2&a[]=3",
+	"a[0]=x&a[1]=y&a[10]=z&a[]=w",
+	"items[2][name]=c&items[0][name]=a&items[x][0]=d",
+	"a=1&a[b]=2&a[b][c]=3",
+	"a]=1&a][b]=2&a][]=3&]=4&[]=5&[=6&a[b=7&a]b[c]=8",
+	"a[-1]=x&a[01]=y&a[+1]=z&a[99999999999999999999]=w",
+	"a.b.c=1&a..b=2&.a=3&a.=4",
+	"a%5Bb%5D=%20&%zz=1&a=%C3%28&a;b=c",
+	"a[[b]]=1&a[]]=2&a[][]=3&a[][b]=4",
+}
+
+func FuzzParser(f *testing.F) {
+	for _, query := range fuzzQueries {
+		f.Add(query)
+	}
+
+	parsers := []*Parser{
+		NewParser(ParseOptions{}),
+		NewParser(ParseOptions{IndexMode: IndexPreserve, AlwaysSlices: true, Limits: Limits{MaxIndex: 1000}}),
+		NewParser(ParseOptions{IndexMode: IndexStrict, Delimiter: CommaDelimiter}),
+		NewParser(ParseOptions{Syntax: DotSyntax}),
+		NewParser(ParseOptions{Syntax: FlatSyntax}),
+		NewParser(ParseOptions{Limits: Limits{MaxDepth: 3, MaxParameters: 10, MaxIndex: 100, MaxValueBytes: 10}}),
+		NewParser(ParseOptions{Malformed: MalformedError, SemicolonSeparator: true}),
+		NewParser(ParseOptions{Malformed: MalformedWarn}),
+	}
+
+	f.Fuzz(
+		func(t *testing.T, rawQuery string) {
+			values, _ := url.ParseQuery(rawQuery)
+
+			for _, parser := range parsers {
+				_, _ = parser.FromValues(values)
+				_, _ = parser.FromRawQuery(rawQuery)
+			}
+			for _, compat := range []Compat{CompatPHP, CompatQS, CompatRack} {
+				_, _ = ParseCompat(rawQuery, compat)
+			}
+
+			// The single-pass parser must behave the same way as the recursive one it replaced
+			want, wantErr := legacyFromValues(values, false, IndexCompact)
+			got, err := NewParser(ParseOptions{}).FromValues(values)
+			if fmt.Sprint(err) != fmt.Sprint(wantErr) || !reflect.DeepEqual(got, want) {
+				t.Errorf("FromValues() = %v, %v, want %v, %v", got, err, want, wantErr)
+			}
+
+			// In the default mode the raw query parser must drop the same pairs as url.ParseQuery
+			ordered, err := FromString(rawQuery, ParseOptions{})
+			if err != nil || !reflect.DeepEqual(ordered.QueryMap, got) {
+				t.Errorf("FromString() = %v, %v, want %v", ordered, err, got)
+			}
+		},
+	)
+}
+
+func FuzzEncodeRoundTrip(f *testing.F) {
+	for _, query := range fuzzQueries {
+		f.Add(query)
+	}
+
+	f.Fuzz(
+		func(t *testing.T, rawQuery string) {
+			values, err := url.ParseQuery(rawQuery)
+			if err != nil {
+				t.Skip()
+			}
+			// Malformed keys ("a][b]", "a[b") are rewritten by the parser and can not be encoded back
+			for key := range values {
+				if !validBrackets(key) {
+					t.Skip()
+				}
+			}
+
+			m := FromValues(values)
+			encoded := m.Encode()
+
+			reparsed, err := url.ParseQuery(encoded)
+			if err != nil {
+				t.Fatalf("url.ParseQuery(%q) error = %v", encoded, err)
+			}
+			if got := FromValues(reparsed); !reflect.DeepEqual(got, m) {
+				t.Errorf("FromValues(%q) = %v, want %v", encoded, got, m)
+			}
+		},
+	)
+}
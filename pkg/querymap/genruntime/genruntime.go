@@ -0,0 +1,351 @@
+// Package genruntime is the runtime of the DecodeQuery and EncodeQuery methods generated by cmd/querymap-gen.
+// It reads and writes url.Values directly, with the same semantics as querymap.FromValuesToStruct
+// and querymap.StructToValues. The generated code is its only intended user.
+package genruntime
+
+import (
+	"fmt"
+	"github.com/KoNekoD/go-querymap/pkg/querymap"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Decoder reads url.Values for a generated DecodeQuery method.
+type Decoder struct {
+	values url.Values
+	errs   []*querymap.FieldError
+}
+
+// NewDecoder creates a Decoder reading `values`.
+func NewDecoder(values url.Values) *Decoder {
+	return &Decoder{values: values}
+}
+
+// Err returns the errors found by the decoder as *querymap.DecodeError, nil if there are none.
+func (d *Decoder) Err() error {
+	if len(d.errs) == 0 {
+		return nil
+	}
+
+	return &querymap.DecodeError{Errors: d.errs}
+}
+
+// Has reports whether the query has a parameter by the `path` or nested under it,
+// so that an optional pointer to a structure is allocated only if it's sent.
+func (d *Decoder) Has(path []string) bool {
+	key := joinPath(path)
+	for name := range d.values {
+		if len(name) < len(key) || !strings.EqualFold(name[:len(key)], key) {
+			continue
+		}
+		if len(name) == len(key) || name[len(key)] == '[' {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Field describes a field of a generated decoder.
+type Field struct {
+	// Parent are the query names of the parent structures: "filter" for "filter[status]".
+	Parent []string
+
+	// Name is the query name of the field.
+	Name string
+
+	// Type is the Go type of the field, as reported in querymap.FieldError.Type: "int", "*int", "[]int".
+	Type string
+
+	// Default is the value of the `default=value` tag option, used if HasDefault.
+	Default    string
+	HasDefault bool
+
+	// Required is set by the `required` tag option.
+	Required bool
+
+	// Delimiter is set by the `comma`, `pipe`, `space` or `delimiter=x` tag options.
+	Delimiter string
+}
+
+// path returns the query names of the field and its parents, a bracketed name is split: "page[size]".
+func (f Field) path() []string {
+	return append(slices.Clone(f.Parent), splitName(f.Name)...)
+}
+
+// DecodeValue decodes a single value of the field with `parse`.
+// Returns false if the field is absent or can't be decoded, the errors are collected by the decoder.
+func DecodeValue[T any](d *Decoder, field Field, parse func(name, value string) (T, error)) (T, bool) {
+	var result T
+
+	values, isList, ok := d.lookup(field)
+	if !ok {
+		return result, false
+	}
+
+	path := joinPath(field.path())
+	if isList || len(values) != 1 {
+		d.errs = append(
+			d.errs, &querymap.FieldError{
+				Path: path, Value: values, Type: field.Type,
+				Message: fmt.Sprintf(
+					"'%s' expected type '%s', got unconvertible type '[]string', value: '%v'",
+					path, strings.TrimPrefix(field.Type, "*"), values,
+				),
+			},
+		)
+		return result, false
+	}
+
+	result, err := parse(path, values[0])
+	if err != nil {
+		d.errs = append(d.errs, &querymap.FieldError{Path: path, Value: values[0], Type: field.Type, Message: err.Error()})
+		return result, false
+	}
+
+	return result, true
+}
+
+// DecodeList decodes the values of a list field with `parse`, element by element.
+// Returns false if the field is absent or can't be decoded, the errors are collected by the decoder.
+func DecodeList[T any](d *Decoder, field Field, parse func(name, value string) (T, error)) ([]T, bool) {
+	values, ok := d.lookupList(field)
+	if !ok {
+		return nil, false
+	}
+
+	if field.Delimiter != "" {
+		var split []string
+		for _, value := range values {
+			split = append(split, strings.Split(value, field.Delimiter)...)
+		}
+		values = split
+	}
+
+	path := joinPath(field.path())
+	elementType := strings.TrimPrefix(field.Type, "[]")
+	result := make([]T, 0, len(values))
+	failed := false
+	for i, value := range values {
+		elementPath := path + "[" + strconv.Itoa(i) + "]"
+		element, err := parse(elementPath, value)
+		if err != nil {
+			d.errs = append(d.errs, &querymap.FieldError{Path: elementPath, Value: value, Type: elementType, Message: err.Error()})
+			failed = true
+			continue
+		}
+		result = append(result, element)
+	}
+
+	return result, !failed
+}
+
+// lookup returns the values of a single-valued field, or its default value.
+// `isList` is set for the values sent as "key[]", which FromValues always reads as a list.
+// Reports the absent required field.
+func (d *Decoder) lookup(field Field) (values []string, isList bool, ok bool) {
+	key := joinPath(field.path())
+	if values, ok := d.values[key]; ok {
+		return values, false, true
+	}
+
+	for _, k := range []string{key, key + "[]"} {
+		for name, values := range d.values {
+			if strings.EqualFold(name, k) {
+				return values, k != key, true
+			}
+		}
+	}
+
+	values, ok = d.absent(field)
+	return values, false, ok
+}
+
+// lookupList returns the values of a list field sent as "key", "key[]" or "key[0]", "key[1]"...
+// (ordered by index), or its default value. Reports the absent required field.
+func (d *Decoder) lookupList(field Field) ([]string, bool) {
+	key := joinPath(field.path())
+
+	var values []string
+	var indexed []indexedValues
+	found := false
+	for name, v := range d.values {
+		switch {
+		case strings.EqualFold(name, key), strings.EqualFold(name, key+"[]"):
+			values = append(values, v...)
+		case len(name) > len(key)+2 && strings.EqualFold(name[:len(key)], key) &&
+			name[len(key)] == '[' && name[len(name)-1] == ']':
+			index, err := strconv.Atoi(name[len(key)+1 : len(name)-1])
+			if err != nil {
+				continue
+			}
+			indexed = append(indexed, indexedValues{index: index, values: v})
+		default:
+			continue
+		}
+		found = true
+	}
+	if !found {
+		return d.absent(field)
+	}
+
+	slices.SortFunc(indexed, func(a, b indexedValues) int { return a.index - b.index })
+	for _, element := range indexed {
+		values = append(values, element.values...)
+	}
+
+	return values, true
+}
+
+// indexedValues are the values of a "key[index]" parameter.
+type indexedValues struct {
+	index  int
+	values []string
+}
+
+// absent returns the default value of the absent field, reporting it if it's required.
+func (d *Decoder) absent(field Field) ([]string, bool) {
+	if field.HasDefault {
+		return []string{field.Default}, true
+	}
+
+	if field.Required {
+		path := joinPath(field.path())
+		d.errs = append(
+			d.errs, &querymap.FieldError{Path: path, Type: field.Type, Message: fmt.Sprintf("'%s' is required", path)},
+		)
+	}
+
+	return nil, false
+}
+
+// The parse functions of the generated decoders convert a raw value the same way querymap.ToStruct does,
+// `name` is the bracket path of the value used in the error messages.
+
+// ParseString returns the value as is.
+func ParseString[T ~string](_ string, value string) (T, error) {
+	return T(value), nil
+}
+
+// ParseBool parses a boolean, an empty value is false.
+func ParseBool[T ~bool](name, value string) (T, error) {
+	if value == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("cannot parse '%s' as bool: %s", name, err)
+	}
+
+	return T(b), nil
+}
+
+// ParseInt parses a signed integer in any base ("10", "0x1f"), an empty value is 0.
+func ParseInt[T ~int | ~int8 | ~int16 | ~int32 | ~int64](name, value string) (T, error) {
+	if value == "" {
+		value = "0"
+	}
+
+	i, err := strconv.ParseInt(value, 0, reflect.TypeFor[T]().Bits())
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse '%s' as int: %s", name, err)
+	}
+
+	return T(i), nil
+}
+
+// ParseUint parses an unsigned integer in any base ("10", "0x1f"), an empty value is 0.
+func ParseUint[T ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr](name, value string) (T, error) {
+	if value == "" {
+		value = "0"
+	}
+
+	i, err := strconv.ParseUint(value, 0, reflect.TypeFor[T]().Bits())
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse '%s' as uint: %s", name, err)
+	}
+
+	return T(i), nil
+}
+
+// ParseFloat parses a floating-point number, an empty value is 0.
+func ParseFloat[T ~float32 | ~float64](name, value string) (T, error) {
+	if value == "" {
+		value = "0"
+	}
+
+	f, err := strconv.ParseFloat(value, reflect.TypeFor[T]().Bits())
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse '%s' as float: %s", name, err)
+	}
+
+	return T(f), nil
+}
+
+// ParseDuration parses a duration like "5s" or a number of nanoseconds as written by querymap.FromStruct.
+func ParseDuration(name, value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err == nil {
+		return duration, nil
+	}
+
+	if nanoseconds, intErr := strconv.ParseInt(value, 10, 64); intErr == nil {
+		return time.Duration(nanoseconds), nil
+	}
+
+	return 0, fmt.Errorf("error decoding '%s': %s", name, err)
+}
+
+// Key returns the bracket key of the field `name` under the `prefix` for the generated EncodeQuery methods.
+func Key(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + "[" + strings.Join(splitName(name), "][") + "]"
+}
+
+// EncodeList writes the elements of a list field for the generated EncodeQuery methods,
+// the same way querymap.StructToValues does:
+// as "key[]" parameters, or as a single value joined by the `delimiter` if it's not empty.
+func EncodeList(values url.Values, key string, list []string, delimiter string) {
+	if len(list) == 0 {
+		return
+	}
+
+	if delimiter != "" {
+		values.Add(key, strings.Join(list, delimiter))
+		return
+	}
+
+	values[key+"[]"] = append(values[key+"[]"], list...)
+}
+
+// joinPath joins the segments into a bracket path: "filters", "2", "price" => "filters[2][price]".
+func joinPath(segments []string) string {
+	path := ""
+	for i, segment := range segments {
+		if i == 0 {
+			path = segment
+			continue
+		}
+		path += "[" + segment + "]"
+	}
+
+	return path
+}
+
+// splitName splits the query name of a field into its segments: "page[size]" => "page", "size".
+func splitName(name string) []string {
+	first, rest, ok := strings.Cut(name, "[")
+	if !ok {
+		return []string{name}
+	}
+
+	return append([]string{first}, strings.Split(strings.TrimSuffix(rest, "]"), "][")...)
+}
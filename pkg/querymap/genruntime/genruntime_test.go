@@ -0,0 +1,85 @@
+package genruntime
+
+import (
+	"github.com/KoNekoD/go-querymap/pkg/querymap"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeValue(t *testing.T) {
+	d := NewDecoder(url.Values{"a": {"0x10"}, "B": {"x"}, "c[]": {"1"}, "d": {"1", "2"}, "i": {"300"}})
+
+	if got, ok := DecodeValue(d, Field{Name: "a", Type: "int8"}, ParseInt[int8]); !ok || got != 16 {
+		t.Errorf("DecodeValue(a) = %v, %v, want 16, true", got, ok)
+	}
+	if got, ok := DecodeValue(d, Field{Name: "b", Type: "string"}, ParseString[string]); !ok || got != "x" {
+		t.Errorf("DecodeValue(b) = %v, %v, want x, true", got, ok)
+	}
+	if got, ok := DecodeValue(d, Field{Name: "e", Type: "int", Default: "5", HasDefault: true}, ParseInt[int]); !ok || got != 5 {
+		t.Errorf("DecodeValue(e) = %v, %v, want 5, true", got, ok)
+	}
+	if _, ok := DecodeValue(d, Field{Name: "f", Type: "int"}, ParseInt[int]); ok {
+		t.Errorf("DecodeValue(f) is ok, want absent")
+	}
+
+	DecodeValue(d, Field{Name: "c", Type: "int"}, ParseInt[int])
+	DecodeValue(d, Field{Name: "d", Type: "*int"}, ParseInt[int])
+	DecodeValue(d, Field{Parent: []string{"g"}, Name: "h[j]", Type: "int", Required: true}, ParseInt[int])
+	DecodeValue(d, Field{Name: "i", Type: "uint8"}, ParseUint[uint8])
+
+	const exceptedMessage = "4 error(s) decoding:\n\n" +
+		"* 'c' expected type 'int', got unconvertible type '[]string', value: '[1]'\n" +
+		"* 'd' expected type 'int', got unconvertible type '[]string', value: '[1 2]'\n" +
+		"* 'g[h][j]' is required\n" +
+		"* cannot parse 'i' as uint: strconv.ParseUint: parsing \"300\": value out of range"
+	if err := d.Err(); err == nil || err.Error() != exceptedMessage {
+		t.Errorf("Expected error to be '%s', got '%v'", exceptedMessage, err)
+	}
+}
+
+func TestDecodeList(t *testing.T) {
+	d := NewDecoder(url.Values{"a[1]": {"3"}, "a[0]": {"2"}, "a": {"1"}, "b": {"x|y", "z"}})
+
+	got, ok := DecodeList(d, Field{Name: "a", Type: "[]int"}, ParseInt[int])
+	if want := []int{1, 2, 3}; !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeList(a) = %v, %v, want %v, true", got, ok, want)
+	}
+
+	strs, ok := DecodeList(d, Field{Name: "b", Type: "[]string", Delimiter: "|"}, ParseString[string])
+	if want := []string{"x", "y", "z"}; !ok || !reflect.DeepEqual(strs, want) {
+		t.Errorf("DecodeList(b) = %v, %v, want %v, true", strs, ok, want)
+	}
+
+	if _, ok := DecodeList(d, Field{Name: "b", Type: "[]float64"}, ParseFloat[float64]); ok {
+		t.Errorf("DecodeList(b) is ok, want an error")
+	}
+	const exceptedMessage = "cannot parse 'b[0]' as float: strconv.ParseFloat: parsing \"x|y\": invalid syntax"
+	if err := d.Err(); err == nil || err.(*querymap.DecodeError).Errors[0].Message != exceptedMessage {
+		t.Errorf("Expected error to be '%s', got '%v'", exceptedMessage, err)
+	}
+}
+
+func TestDecoderHas(t *testing.T) {
+	d := NewDecoder(url.Values{"owner[id]": {"1"}, "ownership": {"x"}})
+
+	if !d.Has([]string{"owner"}) || !d.Has([]string{"Owner", "id"}) {
+		t.Errorf("Has() = false, want true")
+	}
+	if d.Has([]string{"own"}) || d.Has([]string{"owner", "name"}) {
+		t.Errorf("Has() = true, want false")
+	}
+}
+
+func TestEncodeList(t *testing.T) {
+	values := url.Values{}
+	EncodeList(values, Key("", "a"), []string{"1", "2"}, "")
+	EncodeList(values, Key("f", "b"), []string{"1", "2"}, ",")
+	EncodeList(values, Key("f", "c"), nil, ",")
+	EncodeList(values, Key("f", "d[e]"), []string{"1"}, ",")
+
+	want := url.Values{"a[]": {"1", "2"}, "f[b]": {"1,2"}, "f[d][e]": {"1"}}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("EncodeList() = %v, want %v", values, want)
+	}
+}
@@ -0,0 +1,293 @@
+package querymap
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrPathNotFound is matched (via errors.Is) by the *PathError of a path missing in the QueryMap.
+var ErrPathNotFound = errors.New("path not found")
+
+// PathError is returned by the typed getters of QueryMap when the value is missing or can't be converted.
+type PathError struct {
+	// Path is the bracket path of the value, for example "filters[0][price]".
+	Path string
+
+	// Value is the value found by the Path (string, []string, QueryMap...), nil if there is none.
+	Value any
+
+	// Type is the type requested by the getter: "string", "int", "bool", "[]string" or "QueryMap".
+	Type string
+
+	// Err is ErrPathNotFound if there is no value, the parsing error if the value can't be parsed,
+	// nil if the value has an unconvertible type.
+	Err error
+}
+
+func (e *PathError) Error() string {
+	switch {
+	case e.Err == ErrPathNotFound:
+		return fmt.Sprintf("'%s' not found", e.Path)
+	case e.Err != nil:
+		return e.Err.Error()
+	}
+
+	return fmt.Sprintf("'%s' expected type '%s', got unconvertible type '%T', value: '%v'", e.Path, e.Type, e.Value, e.Value)
+}
+
+// Unwrap allows matching the error with errors.Is(err, ErrPathNotFound).
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// Get returns the value by the dot-separated path: "filters.0.price.min" is the value of "filters[0][price][min]".
+// The numeric segments index the slices. A dot of a key is escaped with a backslash as in DotSyntax:
+// `version\.major` is the value of "version.major".
+func (q QueryMap) Get(path string) (any, bool) {
+	return q.getSegments(splitDotPath(path))
+}
+
+// getSegments returns the value by the path segments, see GetPath.
+func (q QueryMap) getSegments(segments []string) (any, bool) {
+	var v any = q
+	for _, segment := range segments {
+		var ok bool
+		if v, ok = child(v, segment, canonicalIndex(segment)); !ok {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// GetPath returns the value by the path elements: GetPath("filters", 0, "price") is the value of "filters[0][price]".
+// An element is either a string or an int, the slices are indexed by both ints and canonical numeric strings ("0", "12"),
+// the maps by both strings and ints (when the index normalization is disabled).
+func (q QueryMap) GetPath(path ...any) (any, bool) {
+	var v any = q
+	for _, element := range path {
+		var ok bool
+		switch element := element.(type) {
+		case string:
+			v, ok = child(v, element, canonicalIndex(element))
+		case int:
+			v, ok = child(v, strconv.Itoa(element), element)
+		}
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// child returns the element of the map `v` by the key or the element of the list `v` by the index.
+func child(v any, key string, index int) (any, bool) {
+	switch value := v.(type) {
+	case QueryMap:
+		v, ok := value[key]
+		return v, ok
+	case anyList:
+		if index >= 0 && index < len(value) {
+			return value[index], true
+		}
+	case []string:
+		if index >= 0 && index < len(value) {
+			return value[index], true
+		}
+	}
+
+	return nil, false
+}
+
+// canonicalIndex returns the index written by the segment ("0", "12"), -1 if it's not a canonical index ("01", "+1", "a").
+func canonicalIndex(segment string) int {
+	if i, err := strconv.Atoi(segment); err == nil && strconv.Itoa(i) == segment {
+		return i
+	}
+
+	return -1
+}
+
+// GetString returns the string by the dot-separated path (see Get).
+// A list with a single value (as parsed with ParseOptions.AlwaysSlices) is accepted as well.
+func (q QueryMap) GetString(path string) (string, error) {
+	return getValue(q, path, "string", parseString)
+}
+
+// GetInt returns the integer by the dot-separated path (see Get), parsed the same way ToStruct does:
+// in any base ("10", "0x1f"), an empty value is 0.
+func (q QueryMap) GetInt(path string) (int, error) {
+	return getValue(q, path, "int", parseInt)
+}
+
+// GetBool returns the boolean by the dot-separated path (see Get), parsed the same way ToStruct does:
+// "1", "t", "true", "0", "f", "false"... an empty value is false.
+func (q QueryMap) GetBool(path string) (bool, error) {
+	return getValue(q, path, "bool", parseBool)
+}
+
+// GetStrings returns the list of strings by the dot-separated path (see Get), a single value is returned as a list.
+// The indexed values ("a[0]=x&a[1]=y") are accepted as long as every element is a single string.
+// The returned slice is a copy and can be modified.
+func (q QueryMap) GetStrings(path string) ([]string, error) {
+	v, bracketPath, pathErr := q.lookup(path, "[]string")
+	if pathErr != nil {
+		return nil, pathErr
+	}
+
+	switch value := v.(type) {
+	case string:
+		return []string{value}, nil
+	case []string:
+		return slices.Clone(value), nil
+	case anyList:
+		strs := make([]string, len(value))
+		for i, element := range value {
+			switch element := element.(type) {
+			case string:
+				strs[i] = element
+			case []string:
+				if len(element) != 1 {
+					return nil, &PathError{Path: bracketPath, Value: v, Type: "[]string"}
+				}
+				strs[i] = element[0]
+			default:
+				return nil, &PathError{Path: bracketPath, Value: v, Type: "[]string"}
+			}
+		}
+		return strs, nil
+	}
+
+	return nil, &PathError{Path: bracketPath, Value: v, Type: "[]string"}
+}
+
+// GetMap returns the nested QueryMap by the dot-separated path (see Get).
+func (q QueryMap) GetMap(path string) (QueryMap, error) {
+	v, bracketPath, pathErr := q.lookup(path, "QueryMap")
+	if pathErr != nil {
+		return nil, pathErr
+	}
+
+	m, ok := v.(QueryMap)
+	if !ok {
+		return nil, &PathError{Path: bracketPath, Value: v, Type: "QueryMap"}
+	}
+
+	return m, nil
+}
+
+// lookup returns the value by the dot-separated path and its bracket path for the errors,
+// or the *PathError of a missing path.
+func (q QueryMap) lookup(path string, typ string) (any, string, *PathError) {
+	segments := splitDotPath(path)
+	bracketPath := joinPath(segments)
+
+	v, ok := q.getSegments(segments)
+	if !ok {
+		return nil, bracketPath, &PathError{Path: bracketPath, Type: typ, Err: ErrPathNotFound}
+	}
+
+	return v, bracketPath, nil
+}
+
+// getValue returns the single value by the dot-separated path converted by `parse`.
+func getValue[T any](q QueryMap, path string, typ string, parse func(name, value string) (T, error)) (T, error) {
+	var zero T
+
+	v, bracketPath, pathErr := q.lookup(path, typ)
+	if pathErr != nil {
+		return zero, pathErr
+	}
+
+	var str string
+	switch value := v.(type) {
+	case string:
+		str = value
+	case []string:
+		if len(value) != 1 {
+			return zero, &PathError{Path: bracketPath, Value: v, Type: typ}
+		}
+		str = value[0]
+	case anyList:
+		var ok bool
+		if len(value) == 1 {
+			str, ok = value[0].(string)
+		}
+		if !ok {
+			return zero, &PathError{Path: bracketPath, Value: v, Type: typ}
+		}
+	default:
+		return zero, &PathError{Path: bracketPath, Value: v, Type: typ}
+	}
+
+	result, err := parse(bracketPath, str)
+	if err != nil {
+		return zero, &PathError{Path: bracketPath, Value: v, Type: typ, Err: err}
+	}
+
+	return result, nil
+}
+
+// The parse functions of the typed getters convert a raw value the same way ToStruct does,
+// `name` is the bracket path of the value used in the error messages.
+
+// parseString returns the value as is.
+func parseString(_ string, value string) (string, error) {
+	return value, nil
+}
+
+// parseInt parses a signed integer in any base ("10", "0x1f"), an empty value is 0.
+func parseInt(name, value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	i, err := strconv.ParseInt(value, 0, strconv.IntSize)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse '%s' as int: %s", name, err)
+	}
+
+	return int(i), nil
+}
+
+// parseBool parses a boolean, an empty value is false.
+func parseBool(name, value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("cannot parse '%s' as bool: %s", name, err)
+	}
+
+	return b, nil
+}
+
+// splitDotPath splits the dot-separated path of the getters into its segments, `\.` is a literal dot.
+func splitDotPath(path string) []string {
+	if !strings.Contains(path, `\.`) {
+		return strings.Split(path, ".")
+	}
+
+	var segments []string
+	var segment strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && path[i+1] == '.':
+			segment.WriteByte('.')
+			i++
+		case path[i] == '.':
+			segments = append(segments, segment.String())
+			segment.Reset()
+		default:
+			segment.WriteByte(path[i])
+		}
+	}
+
+	return append(segments, segment.String())
+}
@@ -0,0 +1,173 @@
+package querymap
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// getterTestQuery is the query string read by the getter tests.
+const getterTestQuery = "filters[0][price][min]=10&filters[0][price][max]=0x20&filters[1][tags][]=a&filters[1][tags][]=b" +
+	"&q=shoes&active=true&empty=&ids=1&ids=2&version.major=3"
+
+func TestQueryMapGet(t *testing.T) {
+	m := mustParse(t, getterTestQuery, ParseOptions{})
+
+	tests := []struct {
+		path string
+		want any
+		ok   bool
+	}{
+		{path: "q", want: "shoes", ok: true},
+		{path: "filters.0.price.min", want: "10", ok: true},
+		{path: "filters.1.tags", want: []string{"a", "b"}, ok: true},
+		{path: "filters.1.tags.1", want: "b", ok: true},
+		{path: "filters.0.price", want: QueryMap{"min": "10", "max": "0x20"}, ok: true},
+		{path: `version\.major`, want: "3", ok: true},
+		{path: "ids.0", want: "1", ok: true},
+		{path: "filters.2"},
+		{path: "filters.01"},
+		{path: "filters.-1"},
+		{path: "filters.x"},
+		{path: "q.0"},
+		{path: "version.major"},
+		{path: "missing.key"},
+	}
+	for _, tt := range tests {
+		got, ok := m.Get(tt.path)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Get(%q) = %v, %v, want %v, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestQueryMapGetPath(t *testing.T) {
+	m := mustParse(t, getterTestQuery, ParseOptions{})
+
+	tests := []struct {
+		path []any
+		want any
+		ok   bool
+	}{
+		{path: []any{"filters", 0, "price", "max"}, want: "0x20", ok: true},
+		{path: []any{"filters", "1", "tags", 0}, want: "a", ok: true},
+		{path: []any{"version.major"}, want: "3", ok: true},
+		{path: []any{}, want: m, ok: true},
+		{path: []any{"filters", 5}},
+		{path: []any{"filters", int64(0)}},
+		{path: []any{"q", "x"}},
+	}
+	for _, tt := range tests {
+		got, ok := m.GetPath(tt.path...)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetPath(%v) = %v, %v, want %v, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	// Without the index normalization the ints index the maps
+	raw, err := NewParser(ParseOptions{DisableIndexNormalization: true}).FromValues(url.Values{"a[0][b]": {"x"}})
+	panicIfErr(err)
+	if got, ok := raw.GetPath("a", 0, "b"); !ok || got != "x" {
+		t.Errorf("GetPath(a, 0, b) = %v, %v, want x, true", got, ok)
+	}
+}
+
+func TestQueryMapTypedGetters(t *testing.T) {
+	m := mustParse(t, getterTestQuery, ParseOptions{})
+
+	if got, err := m.GetString("filters.0.price.min"); err != nil || got != "10" {
+		t.Errorf("GetString() = %v, %v, want 10", got, err)
+	}
+	if got, err := m.GetInt("filters.0.price.max"); err != nil || got != 32 {
+		t.Errorf("GetInt() = %v, %v, want 32", got, err)
+	}
+	if got, err := m.GetInt("empty"); err != nil || got != 0 {
+		t.Errorf("GetInt(empty) = %v, %v, want 0", got, err)
+	}
+	if got, err := m.GetBool("active"); err != nil || !got {
+		t.Errorf("GetBool() = %v, %v, want true", got, err)
+	}
+	if got, err := m.GetStrings("filters.1.tags"); err != nil || !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("GetStrings() = %v, %v, want [a b]", got, err)
+	}
+	if got, err := m.GetStrings("q"); err != nil || !reflect.DeepEqual(got, []string{"shoes"}) {
+		t.Errorf("GetStrings(q) = %v, %v, want [shoes]", got, err)
+	}
+	if got, err := m.GetMap("filters.0.price"); err != nil || got["min"] != "10" {
+		t.Errorf("GetMap() = %v, %v, want the price map", got, err)
+	}
+
+	// The single values of AlwaysSlices and the lists of strings
+	alwaysSlices, err := NewParser(ParseOptions{AlwaysSlices: true}).FromValues(url.Values{"page": {"2"}, "a[0]": {"x"}, "a[1]": {"y"}})
+	panicIfErr(err)
+	if got, err := alwaysSlices.GetInt("page"); err != nil || got != 2 {
+		t.Errorf("GetInt(page) = %v, %v, want 2", got, err)
+	}
+	if got, err := alwaysSlices.GetStrings("a"); err != nil || !reflect.DeepEqual(got, []string{"x", "y"}) {
+		t.Errorf("GetStrings(a) = %v, %v, want [x y]", got, err)
+	}
+}
+
+func TestQueryMapTypedGettersErrors(t *testing.T) {
+	m := mustParse(t, getterTestQuery, ParseOptions{})
+
+	tests := []struct {
+		name     string
+		get      func() error
+		want     string
+		notFound bool
+	}{
+		{
+			name:     "missing",
+			get:      func() error { _, err := m.GetString("filters.3.price"); return err },
+			want:     "'filters[3][price]' not found",
+			notFound: true,
+		},
+		{
+			name: "map as string",
+			get:  func() error { _, err := m.GetString("filters.0.price"); return err },
+			want: "'filters[0][price]' expected type 'string', got unconvertible type 'querymap.QueryMap', value: 'map[max:0x20 min:10]'",
+		},
+		{
+			name: "list as int",
+			get:  func() error { _, err := m.GetInt("ids"); return err },
+			want: "'ids' expected type 'int', got unconvertible type '[]string', value: '[1 2]'",
+		},
+		{
+			name: "invalid int",
+			get:  func() error { _, err := m.GetInt("q"); return err },
+			want: "cannot parse 'q' as int: strconv.ParseInt: parsing \"shoes\": invalid syntax",
+		},
+		{
+			name: "invalid bool",
+			get:  func() error { _, err := m.GetBool("q"); return err },
+			want: "cannot parse 'q' as bool: strconv.ParseBool: parsing \"shoes\": invalid syntax",
+		},
+		{
+			name: "list of maps as strings",
+			get:  func() error { _, err := m.GetStrings("filters"); return err },
+			want: "'filters' expected type '[]string', got unconvertible type 'querymap.anyList', value: '[map[price:map[max:0x20 min:10]] map[tags:[a b]]]'",
+		},
+		{
+			name: "string as map",
+			get:  func() error { _, err := m.GetMap(`version\.major`); return err },
+			want: "'version.major' expected type 'QueryMap', got unconvertible type 'string', value: '3'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				err := tt.get()
+
+				var pathErr *PathError
+				if !errors.As(err, &pathErr) || err.Error() != tt.want {
+					t.Fatalf("Expected error to be '%s', got '%v'", tt.want, err)
+				}
+				if errors.Is(err, ErrPathNotFound) != tt.notFound {
+					t.Errorf("errors.Is(err, ErrPathNotFound) = %v, want %v", !tt.notFound, tt.notFound)
+				}
+			},
+		)
+	}
+}
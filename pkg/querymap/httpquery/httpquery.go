@@ -0,0 +1,142 @@
+// Package httpquery binds the query parameters of net/http requests to structures with querymap,
+// and reports the invalid ones as "application/problem+json" responses (RFC 9457).
+// It depends only on net/http, so it works with any router.
+package httpquery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/KoNekoD/go-querymap/pkg/querymap"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Options configures BindWithOptions and Middleware. The zero value parses and decodes
+// the same way querymap.FromURLToStruct does and writes the errors with WriteProblem.
+type Options struct {
+	// Parse configures the parsing of the query parameters, see querymap.ParseOptions.
+	Parse querymap.ParseOptions
+
+	// Decode configures the decoding of the parameters into the structure, see querymap.DecodeOptions.
+	Decode querymap.DecodeOptions
+
+	// ErrorHandler writes the response of a request whose parameters can't be bound, WriteProblem by default.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// Bind decodes the query parameters of the request into a structure of type T, see querymap.FromURLToStruct.
+// Returns *querymap.DecodeError pointing to the parameters that can't be decoded.
+func Bind[T any](r *http.Request) (*T, error) {
+	return BindWithOptions[T](r, Options{})
+}
+
+// BindWithOptions decodes the query parameters of the request into a structure of type T configured by `options`.
+// Returns the parsing errors (*querymap.LimitError, *querymap.IndexError) and *querymap.DecodeError,
+// see querymap.FromValuesToStructWithOptions.
+func BindWithOptions[T any](r *http.Request, options Options) (*T, error) {
+	return querymap.FromValuesToStructWithOptions[T](r.URL.Query(), options.Parse, options.Decode)
+}
+
+// contextKey is the key of the bound structure of type T in the request context,
+// so the structures of different types don't overwrite each other.
+type contextKey[T any] struct{}
+
+// NewContext returns a copy of the context holding the bound structure, see FromContext.
+func NewContext[T any](ctx context.Context, value *T) context.Context {
+	return context.WithValue(ctx, contextKey[T]{}, value)
+}
+
+// FromContext returns the structure of type T bound by Middleware, false if there is none.
+func FromContext[T any](ctx context.Context) (*T, bool) {
+	value, ok := ctx.Value(contextKey[T]{}).(*T)
+	return value, ok
+}
+
+// Middleware returns a middleware that binds the query parameters of every request into a structure of type T
+// (see BindWithOptions) and stores it in the request context for the next handler (see FromContext).
+// The requests whose parameters can't be bound are answered by Options.ErrorHandler and don't reach the next handler.
+func Middleware[T any](options Options) func(next http.Handler) http.Handler {
+	errorHandler := options.ErrorHandler
+	if errorHandler == nil {
+		errorHandler = WriteProblem
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				value, err := BindWithOptions[T](r, options)
+				if err != nil {
+					errorHandler(w, r, err)
+					return
+				}
+
+				next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), value)))
+			},
+		)
+	}
+}
+
+// Problem is the "application/problem+json" body (RFC 9457) written by WriteProblem.
+type Problem struct {
+	// Type is a URI identifying the problem type, "about:blank" for the plain HTTP status.
+	Type string `json:"type"`
+
+	// Title is the short summary of the problem type, the HTTP status text.
+	Title string `json:"title"`
+
+	// Status is the HTTP status code.
+	Status int `json:"status"`
+
+	// Detail explains this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+
+	// InvalidParams lists the query parameters that can't be decoded.
+	InvalidParams []InvalidParam `json:"invalid-params,omitempty"`
+}
+
+// InvalidParam describes a query parameter that can't be decoded, see querymap.FieldError.
+type InvalidParam struct {
+	// Name is the bracket path of the parameter, for example "filters[2][price]".
+	Name string `json:"name"`
+
+	// Reason is the error message of the parameter.
+	Reason string `json:"reason"`
+}
+
+// NewProblem returns the 400 Bad Request problem describing the binding error,
+// with an InvalidParam for every parameter of *querymap.DecodeError, sorted by the message.
+func NewProblem(err error) *Problem {
+	problem := &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(http.StatusBadRequest),
+		Status: http.StatusBadRequest,
+		Detail: err.Error(),
+	}
+
+	var decodeErr *querymap.DecodeError
+	if errors.As(err, &decodeErr) {
+		problem.Detail = "The query parameters are invalid."
+		for _, fieldErr := range decodeErr.Errors {
+			problem.InvalidParams = append(problem.InvalidParams, InvalidParam{Name: fieldErr.Path, Reason: fieldErr.Message})
+		}
+		// The same order as in the message of the DecodeError
+		slices.SortFunc(
+			problem.InvalidParams, func(a, b InvalidParam) int {
+				return strings.Compare(a.Reason, b.Reason)
+			},
+		)
+	}
+
+	return problem
+}
+
+// WriteProblem writes the binding error as an "application/problem+json" response, see NewProblem.
+func WriteProblem(w http.ResponseWriter, _ *http.Request, err error) {
+	problem := NewProblem(err)
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
@@ -0,0 +1,148 @@
+package httpquery
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/KoNekoD/go-querymap/pkg/querymap"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type listParams struct {
+	Page   int      `query:"page,default=1"`
+	Tags   []string `query:"tags"`
+	Token  string   `query:"token,required"`
+	Filter struct {
+		Status string `query:"status"`
+	} `query:"filter"`
+}
+
+func TestBind(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items?token=t&tags[]=a&tags[]=b&filter[status]=open", nil)
+
+	params, err := Bind[listParams](r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Page != 1 || params.Token != "t" || !reflect.DeepEqual(params.Tags, []string{"a", "b"}) || params.Filter.Status != "open" {
+		t.Errorf("Bind() = %+v", params)
+	}
+}
+
+func TestBindWithOptions(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items?token=t&tags=a,b&pgae=2", nil)
+
+	options := Options{Parse: querymap.ParseOptions{Delimiter: querymap.CommaDelimiter}}
+	params, err := BindWithOptions[listParams](r, options)
+	if err != nil || !reflect.DeepEqual(params.Tags, []string{"a", "b"}) {
+		t.Errorf("BindWithOptions() = %+v, %v, want the comma-delimited tags", params, err)
+	}
+
+	options.Decode.ErrorUnused = true
+	if _, err := BindWithOptions[listParams](r, options); err == nil {
+		t.Errorf("BindWithOptions() error = nil, want the unused parameter 'pgae'")
+	}
+
+	// The errors name the list elements by the indexes of the request
+	r = httptest.NewRequest(http.MethodGet, "/items?token=t&items[0][id]=1&items[2][id]=x", nil)
+	if _, err := BindWithOptions[struct {
+		Items []struct {
+			ID int `query:"id"`
+		} `query:"items"`
+	}](r, Options{}); err == nil || !strings.Contains(err.Error(), "'items[2][id]'") {
+		t.Errorf("BindWithOptions() error = %v, want the parameter 'items[2][id]'", err)
+	}
+
+	options = Options{Parse: querymap.ParseOptions{Limits: querymap.Limits{MaxDepth: 1}}}
+	r = httptest.NewRequest(http.MethodGet, "/items?token=t&filter[a][b]=1", nil)
+	if _, err := BindWithOptions[listParams](r, options); !errors.Is(err, querymap.ErrLimitExceeded) {
+		t.Errorf("BindWithOptions() error = %v, want ErrLimitExceeded", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var bound *listParams
+	handler := Middleware[listParams](Options{})(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				bound, _ = FromContext[listParams](r.Context())
+				if _, ok := FromContext[struct{ Page int }](r.Context()); ok {
+					t.Errorf("FromContext() found a structure of another type")
+				}
+				w.WriteHeader(http.StatusNoContent)
+			},
+		),
+	)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items?token=t&page=3", nil))
+	if w.Code != http.StatusNoContent || bound == nil || bound.Page != 3 || bound.Token != "t" {
+		t.Errorf("Middleware() = %d, %+v, want 204 and the bound parameters", w.Code, bound)
+	}
+}
+
+func TestMiddlewareProblem(t *testing.T) {
+	called := false
+	handler := Middleware[listParams](Options{})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }),
+	)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items?page=x&filter[status][a]=1", nil))
+
+	if called {
+		t.Errorf("Middleware() called the next handler for invalid parameters")
+	}
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("Middleware() = %d %s, want 400 application/problem+json", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	var problem Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	want := Problem{
+		Type:   "about:blank",
+		Title:  "Bad Request",
+		Status: http.StatusBadRequest,
+		Detail: "The query parameters are invalid.",
+		InvalidParams: []InvalidParam{
+			{Name: "filter[status]", Reason: "'filter[status]' expected type 'string', got unconvertible type 'querymap.QueryMap', value: 'map[a:1]'"},
+			{Name: "token", Reason: "'token' is required"},
+			{Name: "page", Reason: "cannot parse 'page' as int: strconv.ParseInt: parsing \"x\": invalid syntax"},
+		},
+	}
+	if !reflect.DeepEqual(problem, want) {
+		t.Errorf("Middleware() problem = %+v, want %+v", problem, want)
+	}
+}
+
+func TestMiddlewareErrorHandler(t *testing.T) {
+	var handled error
+	options := Options{
+		Parse: querymap.ParseOptions{Limits: querymap.Limits{MaxParameters: 1}},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			handled = err
+			w.WriteHeader(http.StatusRequestURITooLong)
+		},
+	}
+	handler := Middleware[listParams](options)(http.NotFoundHandler())
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items?token=t&page=2", nil))
+	if w.Code != http.StatusRequestURITooLong || !errors.Is(handled, querymap.ErrLimitExceeded) {
+		t.Errorf("Middleware() = %d, %v, want the custom error handler", w.Code, handled)
+	}
+}
+
+func TestNewProblem(t *testing.T) {
+	problem := NewProblem(&querymap.LimitError{Limit: "MaxDepth", Key: "a[b][c]", Max: 1})
+
+	want := &Problem{Type: "about:blank", Title: "Bad Request", Status: 400, Detail: "'a[b][c]' exceeds MaxDepth limit of 1"}
+	if !reflect.DeepEqual(problem, want) {
+		t.Errorf("NewProblem() = %+v, want %+v", problem, want)
+	}
+}
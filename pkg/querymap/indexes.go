@@ -0,0 +1,262 @@
+package querymap
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidIndex is matched (via errors.Is) by every *IndexError.
+var ErrInvalidIndex = errors.New("invalid slice index")
+
+// IndexMode defines how maps with numeric keys are converted into slices.
+type IndexMode int
+
+const (
+	// IndexCompact orders the elements by index and drops the gaps between them (default):
+	// "a[0]=x&a[5]=y" => []any{"x", "y"}.
+	IndexCompact IndexMode = iota
+
+	// IndexPreserve keeps every element at its index and fills the gaps with nil:
+	// "a[0]=x&a[2]=y" => []any{"x", nil, "y"}.
+	// Maps with negative or non-canonical ("01", "+1") keys are left as QueryMap.
+	// The slice length is taken from the client input, so the indexes are bounded by
+	// the `maxIndex` of NormalizeIndexes (Limits.MaxIndex of a Parser).
+	IndexPreserve
+
+	// IndexStrict accepts only the indexes 0..n-1 and returns *IndexError
+	// for sparse, negative or non-canonical indexes.
+	IndexStrict
+)
+
+// IndexError is returned when a set of numeric keys can't be converted into a slice in IndexStrict mode.
+type IndexError struct {
+	// Key is the bracket path of the slice, for example "items" or "filters[2][ids]".
+	Key string
+
+	// Index is the offending (or the missing) index.
+	Index string
+
+	// Reason describes why the index is invalid.
+	Reason string
+}
+
+func (e *IndexError) Error() string {
+	return fmt.Sprintf("'%s[%s]': %s", e.Key, e.Index, e.Reason)
+}
+
+// Unwrap allows matching the error with errors.Is(err, ErrInvalidIndex).
+func (e *IndexError) Unwrap() error {
+	return ErrInvalidIndex
+}
+
+// DefaultMaxIndex bounds the indexes kept by IndexPreserve when no maxIndex is given.
+const DefaultMaxIndex = 1000
+
+// NormalizeIndexes recursively checks whether the value is a set of numeric keys,
+// and if so, converts it to a slice (anyList) ordered by index according to the mode.
+// For example, QueryMap{"10": "c", "2": "b", "0": "a"} => []any{"a", "b", "c"} in IndexCompact mode.
+// In IndexPreserve mode an index above `maxIndex` (DefaultMaxIndex if zero) returns *LimitError.
+func NormalizeIndexes(v any, mode IndexMode, maxIndex int) (any, error) {
+	return normalizeIndexes("", v, mode, maxIndex)
+}
+
+// normalizeIndexes - recursive implementation of NormalizeIndexes,
+// the `key` is the bracket path of the value used in errors.
+func normalizeIndexes(key string, v any, mode IndexMode, maxIndex int) (any, error) {
+	switch value := v.(type) {
+	case QueryMap:
+		// First recursively process nested values
+		for k, v := range value {
+			normalized, err := normalizeIndexes(joinKey(key, k), v, mode, maxIndex)
+			if err != nil {
+				return nil, err
+			}
+			value[k] = normalized
+		}
+
+		// If all keys are numbers, turn into a slice
+		entries, ok := indexEntries(value)
+		if !ok {
+			return value, nil
+		}
+
+		list, err := entriesToList(key, entries, mode, maxIndex)
+		if err != nil {
+			return nil, err
+		}
+		if list == nil {
+			return value, nil
+		}
+
+		return list, nil
+	case anyList:
+		entry := make(anyList, len(value))
+		for i, v := range value {
+			normalized, err := normalizeIndexes(joinKey(key, strconv.Itoa(i)), v, mode, maxIndex)
+			if err != nil {
+				return nil, err
+			}
+			entry[i] = normalized
+		}
+		return entry, nil
+	}
+
+	// If not one of the above cases, return as is
+	return v, nil
+}
+
+// indexEntry is a map entry with a numeric key.
+type indexEntry struct {
+	key   string
+	index int
+	value any
+}
+
+// indexEntries returns the map entries ordered by their numeric keys,
+// or false if at least one key is not a number.
+func indexEntries(value QueryMap) ([]indexEntry, bool) {
+	entries := make([]indexEntry, 0, len(value))
+
+	for key, v := range value {
+		index, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, false
+		}
+		entries = append(entries, indexEntry{key: key, index: index, value: v})
+	}
+
+	// Sort by the numeric value, so that "10" follows "2"; "01" and "1" are ordered as strings
+	slices.SortFunc(
+		entries, func(a, b indexEntry) int {
+			if c := cmp.Compare(a.index, b.index); c != 0 {
+				return c
+			}
+			return strings.Compare(a.key, b.key)
+		},
+	)
+
+	return entries, true
+}
+
+// entriesToList converts the ordered entries into a slice according to the mode.
+// Returns nil if the entries should be kept as QueryMap.
+func entriesToList(key string, entries []indexEntry, mode IndexMode, maxIndex int) (anyList, error) {
+	switch mode {
+	case IndexPreserve:
+		if len(entries) == 0 {
+			return anyList{}, nil
+		}
+		for _, entry := range entries {
+			if !isCanonicalIndex(entry) {
+				return nil, nil
+			}
+		}
+
+		if maxIndex <= 0 {
+			maxIndex = DefaultMaxIndex
+		}
+		if last := entries[len(entries)-1]; last.index > maxIndex {
+			return nil, &LimitError{Limit: "MaxIndex", Key: joinKey(key, last.key), Max: maxIndex}
+		}
+
+		list := make(anyList, entries[len(entries)-1].index+1)
+		for _, entry := range entries {
+			list[entry.index] = entry.value
+		}
+		return list, nil
+	case IndexStrict:
+		list := make(anyList, len(entries))
+		for i, entry := range entries {
+			switch {
+			case entry.index < 0:
+				return nil, &IndexError{Key: key, Index: entry.key, Reason: "negative index"}
+			case !isCanonicalIndex(entry):
+				return nil, &IndexError{Key: key, Index: entry.key, Reason: "non-canonical index"}
+			case entry.index != i:
+				return nil, &IndexError{Key: key, Index: strconv.Itoa(i), Reason: "missing index"}
+			}
+			list[i] = entry.value
+		}
+		return list, nil
+	}
+
+	list := make(anyList, len(entries))
+	for i, entry := range entries {
+		list[i] = entry.value
+	}
+
+	return list, nil
+}
+
+// isCanonicalIndex reports whether the key is the canonical form of a non-negative index.
+func isCanonicalIndex(entry indexEntry) bool {
+	return entry.index >= 0 && strconv.Itoa(entry.index) == entry.key
+}
+
+// joinKey appends the `key` to the bracket path `prefix`: ("a", "b") => "a[b]", ("", "a") => "a".
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "[" + key + "]"
+}
+
+// clientPath returns the pathFunc naming the elements of the lists compacted by the parser
+// by the indexes the client sent: with "a[0]=x&a[5]=y" the element "a[1]" is named "a[5]".
+// The other index modes keep the indexes, so they are named by joinPath.
+// The values are parsed again without normalization only when a path is needed, that is on errors.
+func (p *Parser) clientPath(urlQuery url.Values) pathFunc {
+	if p.options.DisableIndexNormalization || p.options.IndexMode != IndexCompact || p.options.Syntax == FlatSyntax {
+		return joinPath
+	}
+
+	var raw QueryMap
+	return func(segments []string) string {
+		if raw == nil {
+			options := p.options
+			options.DisableIndexNormalization = true
+			raw, _ = NewParser(options).FromValues(urlQuery)
+		}
+
+		return joinPath(clientSegments(raw, segments))
+	}
+}
+
+// clientSegments replaces the compacted indexes of the path segments by the keys of the `raw` QueryMap
+// parsed without normalization. The segments not found in `raw` are kept as they are.
+func clientSegments(raw QueryMap, segments []string) []string {
+	result := slices.Clone(segments)
+
+	var v any = raw
+	for i, segment := range segments {
+		data, ok := asQueryMap(v)
+		if !ok {
+			break
+		}
+
+		// The top-level keys are never converted into a slice
+		if entries, ok := indexEntries(data); ok && i > 0 {
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(entries) {
+				break
+			}
+			result[i] = entries[index].key
+			v = entries[index].value
+			continue
+		}
+
+		key, ok := findKey(data, segment)
+		if !ok {
+			break
+		}
+		v = data[key]
+	}
+
+	return result
+}
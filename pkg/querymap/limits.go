@@ -0,0 +1,126 @@
+package querymap
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrLimitExceeded is matched (via errors.Is) by every *LimitError.
+var ErrLimitExceeded = errors.New("query limit exceeded")
+
+// Limits bounds the size of the structure a Parser is allowed to build.
+// A zero (or negative) field disables the corresponding limit.
+type Limits struct {
+	// MaxDepth is the maximum number of nesting levels of a key: "a[b][c]" has a depth of 2.
+	MaxDepth int
+
+	// MaxParameters is the maximum number of values in the query, counting repeated keys.
+	MaxParameters int
+
+	// MaxIndex is the maximum numeric index of a key segment: "a[15]" has an index of 15.
+	// It bounds the length of the slices produced by the index normalization.
+	MaxIndex int
+
+	// MaxValueBytes is the maximum total length of all values in bytes.
+	MaxValueBytes int
+}
+
+// LimitError is returned by a Parser when the query exceeds one of its Limits.
+type LimitError struct {
+	// Limit is the name of the exceeded Limits field, for example "MaxDepth".
+	Limit string
+
+	// Key is the query key that exceeded the limit, empty for limits of the whole query.
+	Key string
+
+	// Max is the configured value of the limit.
+	Max int
+}
+
+func (e *LimitError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("query exceeds %s limit of %d", e.Limit, e.Max)
+	}
+
+	return fmt.Sprintf("'%s' exceeds %s limit of %d", e.Key, e.Limit, e.Max)
+}
+
+// Unwrap allows matching the error with errors.Is(err, ErrLimitExceeded).
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// checkLimits validates the limits of the whole query before any structure is built and any value is split:
+// a delimited value counts as the parameters it's split into (see ParseOptions.Delimiter), without its delimiters.
+// The keys are validated by checkKeyLimits while the structure is built, in sorted order,
+// so the reported error doesn't depend on the map iteration order.
+func (p *Parser) checkLimits(urlQuery url.Values) error {
+	limits := p.options.Limits
+	if limits.MaxParameters <= 0 && limits.MaxValueBytes <= 0 {
+		return nil
+	}
+
+	parameters, valueBytes := 0, 0
+	for key, value := range urlQuery {
+		delimiter := p.delimiter(key)
+		for _, v := range value {
+			parameters++
+			valueBytes += len(v)
+			if delimiter != 0 {
+				delimiters := strings.Count(v, string(delimiter))
+				parameters += delimiters
+				valueBytes -= delimiters * utf8.RuneLen(delimiter)
+			}
+		}
+	}
+
+	if limits.MaxParameters > 0 && parameters > limits.MaxParameters {
+		return &LimitError{Limit: "MaxParameters", Max: limits.MaxParameters}
+	}
+
+	if limits.MaxValueBytes > 0 && valueBytes > limits.MaxValueBytes {
+		return &LimitError{Limit: "MaxValueBytes", Max: limits.MaxValueBytes}
+	}
+
+	return nil
+}
+
+// checkKeyLimits validates the nesting depth and the numeric indexes of a single key.
+func (p *Parser) checkKeyLimits(key string) error {
+	limits := p.options.Limits
+	if limits.MaxDepth <= 0 && limits.MaxIndex <= 0 {
+		return nil
+	}
+
+	// splitKey descends at most once per "[", whether it is closed or not
+	if limits.MaxDepth > 0 && strings.Count(key, "[") > limits.MaxDepth {
+		return &LimitError{Limit: "MaxDepth", Key: key, Max: limits.MaxDepth}
+	}
+
+	if limits.MaxIndex <= 0 {
+		return nil
+	}
+
+	for rest := key; ; {
+		start := strings.IndexByte(rest, '[')
+		if start == -1 {
+			return nil
+		}
+
+		rest = rest[start+1:]
+		end := strings.IndexByte(rest, ']')
+		if end == -1 {
+			return nil
+		}
+
+		if index, err := strconv.Atoi(rest[:end]); err == nil && index > limits.MaxIndex {
+			return &LimitError{Limit: "MaxIndex", Key: key, Max: limits.MaxIndex}
+		}
+
+		rest = rest[end+1:]
+	}
+}
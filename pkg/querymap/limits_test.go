@@ -0,0 +1,169 @@
+package querymap
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParserLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  Limits
+		query   string
+		wantErr *LimitError
+	}{
+		{
+			name:   "no limits",
+			limits: Limits{},
+			query:  "a" + strings.Repeat("[1]", 100) + "=1&b[100000]=2",
+		},
+		{
+			name:   "depth within limit",
+			limits: Limits{MaxDepth: 2},
+			query:  "a[b][c]=1&d=2",
+		},
+		{
+			name:    "depth exceeded",
+			limits:  Limits{MaxDepth: 2},
+			query:   "a[b][c][d]=1",
+			wantErr: &LimitError{Limit: "MaxDepth", Key: "a[b][c][d]", Max: 2},
+		},
+		{
+			name:    "depth exceeded by unclosed brackets",
+			limits:  Limits{MaxDepth: 3},
+			query:   "a" + strings.Repeat("[b", 50) + "=1",
+			wantErr: &LimitError{Limit: "MaxDepth", Key: "a" + strings.Repeat("[b", 50), Max: 3},
+		},
+		{
+			name:   "unclosed brackets within limit",
+			limits: Limits{MaxDepth: 3},
+			query:  "a[[=1&b[=2",
+		},
+		{
+			name:    "first key in sorted order is reported",
+			limits:  Limits{MaxDepth: 1, MaxIndex: 5},
+			query:   "b[6]=1&a[b][c]=2&c[x][y]=3",
+			wantErr: &LimitError{Limit: "MaxDepth", Key: "a[b][c]", Max: 1},
+		},
+		{
+			name:    "query limits are reported before key limits",
+			limits:  Limits{MaxDepth: 1, MaxParameters: 1},
+			query:   "a[b][c]=1&d=2",
+			wantErr: &LimitError{Limit: "MaxParameters", Max: 1},
+		},
+		{
+			name:   "parameters within limit",
+			limits: Limits{MaxParameters: 3},
+			query:  "a=1&a=2&b=3",
+		},
+		{
+			name:    "parameters exceeded by repeated keys",
+			limits:  Limits{MaxParameters: 3},
+			query:   "a=1&a=2&a=3&b=4",
+			wantErr: &LimitError{Limit: "MaxParameters", Max: 3},
+		},
+		{
+			name:   "index within limit",
+			limits: Limits{MaxIndex: 20},
+			query:  "a[20]=1&b[x][0]=2",
+		},
+		{
+			name:    "index exceeded",
+			limits:  Limits{MaxIndex: 20},
+			query:   "a[0]=1&b[x][21]=2",
+			wantErr: &LimitError{Limit: "MaxIndex", Key: "b[x][21]", Max: 20},
+		},
+		{
+			name:   "value bytes within limit",
+			limits: Limits{MaxValueBytes: 6},
+			query:  "a=abc&b=def",
+		},
+		{
+			name:    "value bytes exceeded",
+			limits:  Limits{MaxValueBytes: 6},
+			query:   "a=abc&b=defg",
+			wantErr: &LimitError{Limit: "MaxValueBytes", Max: 6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				values, err := url.ParseQuery(tt.query)
+				panicIfErr(err)
+
+				got, err := NewParser(ParseOptions{Limits: tt.limits}).FromValues(values)
+				if tt.wantErr == nil {
+					panicIfErr(err)
+					if want := FromValues(values); !reflect.DeepEqual(got, want) {
+						t.Errorf("Parser.FromValues() = %v, want %v", got, want)
+					}
+					return
+				}
+
+				var limitErr *LimitError
+				if !errors.As(err, &limitErr) {
+					t.Fatalf("Expected *LimitError, got %v", err)
+				}
+				if !reflect.DeepEqual(limitErr, tt.wantErr) {
+					t.Errorf("Parser.FromValues() error = %+v, want %+v", limitErr, tt.wantErr)
+				}
+				if !errors.Is(err, ErrLimitExceeded) {
+					t.Errorf("Expected error to match ErrLimitExceeded")
+				}
+				if got != nil {
+					t.Errorf("Expected nil QueryMap on error, got %v", got)
+				}
+			},
+		)
+	}
+}
+
+func TestLimitErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  *LimitError
+		want string
+	}{
+		{err: &LimitError{Limit: "MaxDepth", Key: "a[b]", Max: 1}, want: "'a[b]' exceeds MaxDepth limit of 1"},
+		{err: &LimitError{Limit: "MaxParameters", Max: 10}, want: "query exceeds MaxParameters limit of 10"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestParserLimitsDelimitedValues(t *testing.T) {
+	options := ParseOptions{
+		Delimiter:     CommaDelimiter,
+		KeyDelimiters: map[string]rune{"ids": PipeDelimiter, "raw": 0},
+		Limits:        Limits{MaxParameters: 4, MaxValueBytes: 4},
+	}
+	parser := NewParser(options)
+
+	tests := []struct {
+		query   string
+		wantErr string
+	}{
+		{query: "tags=a,b&ids=c|d"},
+		{query: "tags=a,b,c&ids=d|e", wantErr: "query exceeds MaxParameters limit of 4"},
+		{query: "tags=a,b,c,d"},
+		{query: "raw=a,b,c", wantErr: "query exceeds MaxValueBytes limit of 4"},
+		{query: "tags=" + strings.Repeat(",", 1<<20), wantErr: "query exceeds MaxParameters limit of 4"},
+	}
+	for _, tt := range tests {
+		values, err := url.ParseQuery(tt.query)
+		panicIfErr(err)
+
+		_, err = parser.FromValues(values)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("Parser.FromValues(%.20q) error = %v", tt.query, err)
+		case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+			t.Errorf("Parser.FromValues(%.20q) error = %v, want %s", tt.query, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package querymap
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/exp/maps"
+	"slices"
+)
+
+// ErrUnsupportedValue is returned when a value can't be stored in a QueryMap.
+// The supported values are string, []string, QueryMap (or map[string]any) and []any of them.
+var ErrUnsupportedValue = errors.New("unsupported query value")
+
+// ErrMergeConflict is matched (via errors.Is) by every *ConflictError.
+var ErrMergeConflict = errors.New("merge conflict")
+
+// ConflictError is returned by QueryMap.Merge with MergeErrorOnConflict when both maps hold a value by the same path.
+type ConflictError struct {
+	// Path is the bracket path of the conflicting values, for example "filters[status]".
+	Path string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("'%s' is already set", e.Path)
+}
+
+// Unwrap allows matching the error with errors.Is(err, ErrMergeConflict).
+func (e *ConflictError) Unwrap() error {
+	return ErrMergeConflict
+}
+
+// MergeStrategy defines how QueryMap.Merge combines the values present in both maps.
+type MergeStrategy int
+
+const (
+	// MergeAppend combines the values the same way the parser combines repeated keys (default):
+	// "a" + "b" => []string{"a", "b"}, the maps are merged recursively, a string and a map form a list.
+	MergeAppend MergeStrategy = iota
+
+	// MergeOverwrite replaces the top-level values with the ones of the other map.
+	MergeOverwrite
+
+	// MergeDeep merges the maps recursively and replaces the other values (strings and lists) with the ones of the other map.
+	MergeDeep
+
+	// MergeErrorOnConflict merges the maps recursively and returns *ConflictError if both maps hold a value
+	// that is not a map by the same path. The QueryMap is left unchanged on error.
+	MergeErrorOnConflict
+)
+
+// Set sets the value by the dot-separated path (see Get), replacing the existing value and creating the missing maps:
+// Set("filters.status", "open") sets "filters[status]". An index equal to the length of a list appends to the list.
+// Returns *PathError if the path goes through a value that is not a map or a list, or past the end of a list.
+func (q QueryMap) Set(path string, value any) error {
+	value, err := copyValue(value)
+	if err != nil {
+		return err
+	}
+
+	_, err = setSegments(q, splitDotPath(path), 0, value, false)
+	return err
+}
+
+// Append adds the value to the one by the dot-separated path (see Get) the same way the parser combines
+// repeated keys: Append("tags", "b") on "tags=a" gives "tags=a&tags=b". A missing value is set as is.
+func (q QueryMap) Append(path string, value any) error {
+	value, err := copyValue(value)
+	if err != nil {
+		return err
+	}
+
+	_, err = setSegments(q, splitDotPath(path), 0, value, true)
+	return err
+}
+
+// Delete removes the value by the dot-separated path (see Get), the following elements of a list are shifted.
+// Returns false if there is no value by the path.
+func (q QueryMap) Delete(path string) bool {
+	_, ok := deleteSegments(q, splitDotPath(path))
+	return ok
+}
+
+// Merge merges the other QueryMap into this one according to the strategy, see MergeStrategy.
+// The values of `other` are copied, so the maps don't share any nested values after the merge.
+func (q QueryMap) Merge(other QueryMap, strategy MergeStrategy) error {
+	copied, err := copyValue(other)
+	if err != nil {
+		return err
+	}
+	src := copied.(QueryMap)
+
+	switch strategy {
+	case MergeOverwrite:
+		maps.Copy(q, src)
+	case MergeDeep:
+		mergeDeep(q, src)
+	case MergeErrorOnConflict:
+		if err := findConflict(q, src, nil); err != nil {
+			return err
+		}
+		mergeDeep(q, src)
+	default:
+		for key, value := range src {
+			q.set(key, value)
+		}
+	}
+
+	return nil
+}
+
+// setSegments sets (or appends, see QueryMap.Append) the value by the path segments starting at `i` in `v`
+// and returns the new value of `v`: the lists are reallocated when they grow.
+func setSegments(v any, segments []string, i int, value any, appendValue bool) (any, error) {
+	key := segments[i]
+	last := i == len(segments)-1
+
+	switch container := v.(type) {
+	case QueryMap:
+		child, ok := container[key]
+		switch {
+		case last && appendValue:
+			container.set(key, value)
+		case last:
+			container[key] = value
+		default:
+			if !ok {
+				child = newQueryMap()
+			}
+			newChild, err := setSegments(child, segments, i+1, value, appendValue)
+			if err != nil {
+				return nil, err
+			}
+			container[key] = newChild
+		}
+		return container, nil
+
+	case []string:
+		if str, ok := value.(string); ok && last && !appendValue {
+			index := canonicalIndex(key)
+			switch {
+			case index >= 0 && index < len(container):
+				container[index] = str
+				return container, nil
+			case index == len(container):
+				return append(container, str), nil
+			}
+		}
+
+		list := make(anyList, len(container))
+		for j, str := range container {
+			list[j] = str
+		}
+		return setSegments(list, segments, i, value, appendValue)
+
+	case anyList:
+		index := canonicalIndex(key)
+		if index < 0 || index > len(container) {
+			return nil, &PathError{Path: joinPath(segments[:i+1]), Value: v, Err: ErrPathNotFound}
+		}
+
+		var child any
+		if index < len(container) {
+			child = container[index]
+		}
+
+		switch {
+		case last && appendValue && child != nil:
+			// The elements are merged the same way the values of a QueryMap are
+			merged := QueryMap{key: child}.set(key, value)
+			child = merged[key]
+		case last:
+			child = value
+		default:
+			if child == nil {
+				child = newQueryMap()
+			}
+			newChild, err := setSegments(child, segments, i+1, value, appendValue)
+			if err != nil {
+				return nil, err
+			}
+			child = newChild
+		}
+
+		if index == len(container) {
+			return append(container, child), nil
+		}
+		container[index] = child
+		return container, nil
+	}
+
+	return nil, &PathError{Path: joinPath(segments[:i]), Value: v, Type: "QueryMap"}
+}
+
+// deleteSegments removes the value by the path segments from `v` and returns the new value of `v`:
+// the lists are shortened.
+func deleteSegments(v any, segments []string) (any, bool) {
+	key := segments[0]
+	last := len(segments) == 1
+
+	switch container := v.(type) {
+	case QueryMap:
+		child, ok := container[key]
+		if !ok {
+			return v, false
+		}
+		if last {
+			delete(container, key)
+			return v, true
+		}
+		container[key], ok = deleteSegments(child, segments[1:])
+		return v, ok
+
+	case []string:
+		index := canonicalIndex(key)
+		if !last || index < 0 || index >= len(container) {
+			return v, false
+		}
+		return slices.Delete(container, index, index+1), true
+
+	case anyList:
+		index := canonicalIndex(key)
+		if index < 0 || index >= len(container) {
+			return v, false
+		}
+		if last {
+			return slices.Delete(container, index, index+1), true
+		}
+		var ok bool
+		container[index], ok = deleteSegments(container[index], segments[1:])
+		return v, ok
+	}
+
+	return v, false
+}
+
+// mergeDeep merges `src` into `dst` recursively, the values that are not maps are replaced.
+func mergeDeep(dst, src QueryMap) {
+	for key, value := range src {
+		dstMap, dstOk := dst[key].(QueryMap)
+		srcMap, srcOk := value.(QueryMap)
+		if dstOk && srcOk {
+			mergeDeep(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// findConflict returns *ConflictError for the first path (in key order) holding a value that is not a map in both maps.
+func findConflict(dst, src QueryMap, path []string) error {
+	keys := maps.Keys(src)
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		dstValue, ok := dst[key]
+		if !ok {
+			continue
+		}
+
+		keyPath := append(path[:len(path):len(path)], key)
+		dstMap, dstOk := dstValue.(QueryMap)
+		srcMap, srcOk := src[key].(QueryMap)
+		if !dstOk || !srcOk {
+			return &ConflictError{Path: joinPath(keyPath)}
+		}
+		if err := findConflict(dstMap, srcMap, keyPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyValue returns a deep copy of the value in the QueryMap types: map[string]any is converted into QueryMap
+// and []any into anyList. A nil value (the gaps of IndexPreserve lists) is kept as it is.
+// Returns ErrUnsupportedValue for the values of other types.
+func copyValue(v any) (any, error) {
+	switch value := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return value, nil
+	case []string:
+		return slices.Clone(value), nil
+	case QueryMap:
+		return copyMap(value)
+	case map[string]any:
+		return copyMap(value)
+	case anyList:
+		return copyList(value)
+	case []any:
+		return copyList(value)
+	}
+
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedValue, v)
+}
+
+// copyMap returns a deep copy of the map as QueryMap, see copyValue.
+func copyMap[M ~map[string]any](m M) (any, error) {
+	result := make(QueryMap, len(m))
+	for key, value := range m {
+		copied, err := copyValue(value)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = copied
+	}
+
+	return result, nil
+}
+
+// copyList returns a deep copy of the list as anyList, see copyValue.
+func copyList[L ~[]any](list L) (any, error) {
+	result := make(anyList, len(list))
+	for i, value := range list {
+		copied, err := copyValue(value)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = copied
+	}
+
+	return result, nil
+}
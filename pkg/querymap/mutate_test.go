@@ -0,0 +1,208 @@
+package querymap
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// mutateTestQuery is the query string changed by the mutation tests.
+const mutateTestQuery = "q=shoes&ids=1&ids=2&filters[0][status]=open&filters[1][status]=closed&page[size]=10"
+
+func TestQueryMapSetPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		value any
+		want  string
+	}{
+		{name: "replace", path: "q", value: "boots", want: "filters[0][status]=open&filters[1][status]=closed&ids[]=1&ids[]=2&page[size]=10&q=boots"},
+		{name: "new nested", path: "sort.by", value: "name", want: "filters[0][status]=open&filters[1][status]=closed&ids[]=1&ids[]=2&page[size]=10&q=shoes&sort[by]=name"},
+		{name: "list element", path: "filters.1.status", value: "draft", want: "filters[0][status]=open&filters[1][status]=draft&ids[]=1&ids[]=2&page[size]=10&q=shoes"},
+		{name: "list end", path: "filters.2.status", value: "new", want: "filters[0][status]=open&filters[1][status]=closed&filters[2][status]=new&ids[]=1&ids[]=2&page[size]=10&q=shoes"},
+		{name: "strings element", path: "ids.1", value: "3", want: "filters[0][status]=open&filters[1][status]=closed&ids[]=1&ids[]=3&page[size]=10&q=shoes"},
+		{name: "strings end", path: "ids.2", value: "3", want: "filters[0][status]=open&filters[1][status]=closed&ids[]=1&ids[]=2&ids[]=3&page[size]=10&q=shoes"},
+		{name: "strings map element", path: "ids.2.x", value: "3", want: "filters[0][status]=open&filters[1][status]=closed&ids[0]=1&ids[1]=2&ids[2][x]=3&page[size]=10&q=shoes"},
+		{name: "map value", path: "page", value: map[string]any{"size": "5", "tags": []any{"a", "b"}}, want: "filters[0][status]=open&filters[1][status]=closed&ids[]=1&ids[]=2&page[size]=5&page[tags][0]=a&page[tags][1]=b&q=shoes"},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				m := mustParse(t, mutateTestQuery, ParseOptions{})
+				if err := m.Set(tt.path, tt.value); err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := url.QueryUnescape(m.Encode())
+				panicIfErr(err)
+				if got != tt.want {
+					t.Errorf("Set(%q) = %s, want %s", tt.path, got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestQueryMapSetPathErrors(t *testing.T) {
+	tests := []struct {
+		path  string
+		value any
+		want  string
+	}{
+		{path: "q.x", value: "1", want: "'q' expected type 'QueryMap', got unconvertible type 'string', value: 'shoes'"},
+		{path: "ids.1.x", value: "1", want: "'ids[1]' expected type 'QueryMap', got unconvertible type 'string', value: '2'"},
+		{path: "filters.5.status", value: "1", want: "'filters[5]' not found"},
+		{path: "filters.x", value: "1", want: "'filters[x]' not found"},
+		{path: "page", value: 10, want: "unsupported query value: int"},
+		{path: "page", value: map[string]any{"size": 10}, want: "unsupported query value: int"},
+	}
+	for _, tt := range tests {
+		m := mustParse(t, mutateTestQuery, ParseOptions{})
+		if err := m.Set(tt.path, tt.value); err == nil || err.Error() != tt.want {
+			t.Errorf("Set(%q) error = %v, want %s", tt.path, err, tt.want)
+		}
+	}
+
+	if err := mustParse(t, mutateTestQuery, ParseOptions{}).Set("page", 10); !errors.Is(err, ErrUnsupportedValue) {
+		t.Errorf("Set() error = %v, want ErrUnsupportedValue", err)
+	}
+}
+
+func TestQueryMapAppend(t *testing.T) {
+	m := mustParse(t, mutateTestQuery, ParseOptions{})
+	panicIfErr(m.Append("ids", "3"))
+	panicIfErr(m.Append("q", []string{"boots"}))
+	panicIfErr(m.Append("page", QueryMap{"number": "2"}))
+	panicIfErr(m.Append("filters.0.status", "draft"))
+	panicIfErr(m.Append("new.key", "x"))
+
+	want := QueryMap{
+		"q":   []string{"shoes", "boots"},
+		"ids": []string{"1", "2", "3"},
+		"filters": anyList{
+			QueryMap{"status": []string{"open", "draft"}},
+			QueryMap{"status": "closed"},
+		},
+		"page": QueryMap{"size": "10", "number": "2"},
+		"new":  QueryMap{"key": "x"},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("Append() = %v, want %v", m, want)
+	}
+}
+
+func TestQueryMapDelete(t *testing.T) {
+	m := mustParse(t, mutateTestQuery, ParseOptions{})
+
+	for _, path := range []string{"q", "ids.0", "filters.0", "page.size"} {
+		if !m.Delete(path) {
+			t.Errorf("Delete(%q) = false, want true", path)
+		}
+	}
+	for _, path := range []string{"q", "ids.5", "filters.x", "missing.key", "ids.0.x"} {
+		if m.Delete(path) {
+			t.Errorf("Delete(%q) = true, want false", path)
+		}
+	}
+
+	want := QueryMap{"ids": []string{"2"}, "filters": anyList{QueryMap{"status": "closed"}}, "page": QueryMap{}}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("Delete() = %v, want %v", m, want)
+	}
+}
+
+func TestQueryMapMerge(t *testing.T) {
+	other := QueryMap{"q": "boots", "page": QueryMap{"size": "20", "number": "2"}, "sort": "name"}
+
+	tests := []struct {
+		strategy MergeStrategy
+		want     QueryMap
+	}{
+		{
+			strategy: MergeAppend,
+			want: QueryMap{
+				"q": []string{"shoes", "boots"}, "page": QueryMap{"size": []string{"10", "20"}, "number": "2"}, "sort": "name",
+			},
+		},
+		{
+			strategy: MergeOverwrite,
+			want:     QueryMap{"q": "boots", "page": QueryMap{"size": "20", "number": "2"}, "sort": "name"},
+		},
+		{
+			strategy: MergeDeep,
+			want:     QueryMap{"q": "boots", "page": QueryMap{"size": "20", "number": "2"}, "sort": "name"},
+		},
+	}
+	for _, tt := range tests {
+		m := QueryMap{"q": "shoes", "page": QueryMap{"size": "10"}}
+		if err := m.Merge(other, tt.strategy); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, tt.want) {
+			t.Errorf("Merge(%d) = %v, want %v", tt.strategy, m, tt.want)
+		}
+	}
+
+	// The deep merge keeps the keys missing in the other map, the overwrite doesn't
+	deep, overwrite := QueryMap{"page": QueryMap{"size": "10", "cursor": "c"}}, QueryMap{"page": QueryMap{"size": "10", "cursor": "c"}}
+	panicIfErr(deep.Merge(QueryMap{"page": QueryMap{"size": "20"}}, MergeDeep))
+	panicIfErr(overwrite.Merge(QueryMap{"page": QueryMap{"size": "20"}}, MergeOverwrite))
+	if want := (QueryMap{"page": QueryMap{"size": "20", "cursor": "c"}}); !reflect.DeepEqual(deep, want) {
+		t.Errorf("Merge(MergeDeep) = %v, want %v", deep, want)
+	}
+	if want := (QueryMap{"page": QueryMap{"size": "20"}}); !reflect.DeepEqual(overwrite, want) {
+		t.Errorf("Merge(MergeOverwrite) = %v, want %v", overwrite, want)
+	}
+
+	// The merged values are copies
+	m := QueryMap{}
+	panicIfErr(m.Merge(other, MergeAppend))
+	m["page"].(QueryMap)["size"] = "30"
+	if other["page"].(QueryMap)["size"] != "20" {
+		t.Errorf("Merge() shares the nested maps with the other map")
+	}
+}
+
+func TestQueryMapMergeIndexPreserve(t *testing.T) {
+	values, err := url.ParseQuery("ids[0]=a&ids[2]=c")
+	panicIfErr(err)
+	other, err := FromValuesWithOptions(values, ParseOptions{IndexMode: IndexPreserve})
+	panicIfErr(err)
+
+	for _, strategy := range []MergeStrategy{MergeAppend, MergeOverwrite, MergeDeep, MergeErrorOnConflict} {
+		m := QueryMap{"q": "shoes"}
+		if err := m.Merge(other, strategy); err != nil {
+			t.Fatalf("Merge(%d) error = %v", strategy, err)
+		}
+		if want := (QueryMap{"q": "shoes", "ids": anyList{"a", nil, "c"}}); !reflect.DeepEqual(m, want) {
+			t.Errorf("Merge(%d) = %v, want %v", strategy, m, want)
+		}
+	}
+
+	m := QueryMap{}
+	if err := m.Set("ids", other["ids"]); err != nil || !reflect.DeepEqual(m["ids"], anyList{"a", nil, "c"}) {
+		t.Errorf("Set() = %v, %v, want the list with its gap", m, err)
+	}
+}
+
+func TestQueryMapMergeErrorOnConflict(t *testing.T) {
+	m := QueryMap{"q": "shoes", "page": QueryMap{"size": "10"}}
+
+	if err := m.Merge(QueryMap{"page": QueryMap{"number": "2"}, "sort": "name"}, MergeErrorOnConflict); err != nil {
+		t.Fatal(err)
+	}
+	want := QueryMap{"q": "shoes", "page": QueryMap{"size": "10", "number": "2"}, "sort": "name"}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("Merge() = %v, want %v", m, want)
+	}
+
+	err := m.Merge(QueryMap{"a": "1", "page": QueryMap{"cursor": "c", "size": "20"}}, MergeErrorOnConflict)
+	var conflictErr *ConflictError
+	if !errors.As(err, &conflictErr) || !errors.Is(err, ErrMergeConflict) || err.Error() != "'page[size]' is already set" {
+		t.Fatalf("Merge() error = %v, want 'page[size]' is already set", err)
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("Merge() changed the map on error: %v, want %v", m, want)
+	}
+}
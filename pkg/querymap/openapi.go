@@ -0,0 +1,387 @@
+package querymap
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ParamStyle is the OpenAPI 3 `style` of a query parameter.
+type ParamStyle int
+
+const (
+	// StyleForm - "color=blue,black" (or "color=blue&color=black" exploded), the OpenAPI default.
+	StyleForm ParamStyle = iota
+	// StyleSpaceDelimited - "color=blue%20black", for arrays and objects.
+	StyleSpaceDelimited
+	// StylePipeDelimited - "color=blue|black", for arrays and objects.
+	StylePipeDelimited
+	// StyleDeepObject - "color[R]=100&color[G]=200", for exploded objects.
+	StyleDeepObject
+)
+
+// Explode is the OpenAPI 3 `explode` flag of a query parameter.
+type Explode int
+
+const (
+	// ExplodeDefault follows the OpenAPI default: exploded for StyleForm and StyleDeepObject, not exploded otherwise.
+	ExplodeDefault Explode = iota
+	// Exploded writes the array elements and the object properties as separate parameters.
+	Exploded
+	// NotExploded writes the array elements and the object properties as a single delimited value.
+	NotExploded
+)
+
+// ParamKind is the OpenAPI 3 schema type of a query parameter.
+type ParamKind int
+
+const (
+	// KindPrimitive is a single value, parsed as string.
+	KindPrimitive ParamKind = iota
+	// KindArray is a list of values, parsed as []string.
+	KindArray
+	// KindObject is a set of properties, parsed as QueryMap.
+	KindObject
+)
+
+// ParamSpec describes how a query parameter is serialized, as the `parameters` of an OpenAPI 3 operation.
+type ParamSpec struct {
+	// Name is the name of the parameter.
+	Name string
+
+	Style   ParamStyle
+	Explode Explode
+	Kind    ParamKind
+
+	// Properties are the names of the object properties, used by the exploded StyleForm objects,
+	// whose properties are written as separate parameters ("R=100&G=200").
+	// If empty, such an object collects all the parameters not described by other specs.
+	Properties []string
+}
+
+// exploded returns the effective `explode` flag of the spec.
+func (s ParamSpec) exploded() bool {
+	if s.Explode == ExplodeDefault {
+		return s.Style == StyleForm || s.Style == StyleDeepObject
+	}
+
+	return s.Explode == Exploded
+}
+
+// delimiter returns the delimiter of the non-exploded values of the spec.
+func (s ParamSpec) delimiter() string {
+	switch s.Style {
+	case StyleSpaceDelimited:
+		return string(SpaceDelimiter)
+	case StylePipeDelimited:
+		return string(PipeDelimiter)
+	}
+
+	return string(CommaDelimiter)
+}
+
+// validate checks that the style, explode and kind combination is defined by OpenAPI.
+func (s ParamSpec) validate() error {
+	switch {
+	case s.Style == StyleSpaceDelimited || s.Style == StylePipeDelimited:
+		if s.Kind == KindPrimitive {
+			return &SpecError{Name: s.Name, Reason: "delimited styles are not defined for primitive values"}
+		}
+	case s.Style == StyleDeepObject:
+		if s.Kind != KindObject || !s.exploded() {
+			return &SpecError{Name: s.Name, Reason: "deepObject style is defined only for exploded objects"}
+		}
+	}
+
+	return nil
+}
+
+// SpecError is returned when a parameter doesn't match its ParamSpec, or the spec itself is invalid.
+type SpecError struct {
+	// Name is the name of the parameter.
+	Name string
+
+	// Reason describes the error.
+	Reason string
+}
+
+func (e *SpecError) Error() string {
+	return fmt.Sprintf("'%s': %s", e.Name, e.Reason)
+}
+
+// ParamSpecs describes the query parameters of an operation, see ParamSpec.
+// The parameters not described by the specs are ignored.
+type ParamSpecs []ParamSpec
+
+// FromValues parses the url.Values according to the specs: the primitives are returned as string,
+// the arrays as []string and the objects as QueryMap. The absent parameters are omitted.
+// Returns *SpecError if a parameter doesn't match its spec.
+func (s ParamSpecs) FromValues(urlQuery url.Values) (QueryMap, error) {
+	data := newQueryMap()
+
+	for _, spec := range s {
+		if err := spec.validate(); err != nil {
+			return nil, err
+		}
+
+		value, err := s.parseParam(spec, urlQuery)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			data[spec.Name] = value
+		}
+	}
+
+	return data, nil
+}
+
+// parseParam parses a single parameter, returns nil if it's absent.
+func (s ParamSpecs) parseParam(spec ParamSpec, urlQuery url.Values) (any, error) {
+	if spec.Kind == KindObject && spec.exploded() {
+		return s.parseExplodedObject(spec, urlQuery), nil
+	}
+
+	values, ok := urlQuery[spec.Name]
+	if !ok {
+		return nil, nil
+	}
+
+	switch spec.Kind {
+	case KindArray:
+		if spec.exploded() {
+			return slices.Clone(values), nil
+		}
+		if len(values) > 1 {
+			return nil, &SpecError{Name: spec.Name, Reason: "expected a single delimited value"}
+		}
+		// An empty array is written as "color="
+		if values[0] == "" {
+			return []string{}, nil
+		}
+		return strings.Split(values[0], spec.delimiter()), nil
+	case KindObject:
+		if len(values) > 1 {
+			return nil, &SpecError{Name: spec.Name, Reason: "expected a single delimited value"}
+		}
+		if values[0] == "" {
+			return newQueryMap(), nil
+		}
+		pairs := strings.Split(values[0], spec.delimiter())
+		if len(pairs)%2 != 0 {
+			return nil, &SpecError{Name: spec.Name, Reason: "expected property names and values in pairs"}
+		}
+		object := newQueryMap()
+		for i := 0; i < len(pairs); i += 2 {
+			object[pairs[i]] = pairs[i+1]
+		}
+		return object, nil
+	}
+
+	if len(values) > 1 {
+		return nil, &SpecError{Name: spec.Name, Reason: "expected a single value"}
+	}
+
+	return values[0], nil
+}
+
+// parseExplodedObject parses the properties of an exploded object:
+// "color[R]=100" for StyleDeepObject, "R=100" for StyleForm. Returns nil if there are none.
+func (s ParamSpecs) parseExplodedObject(spec ParamSpec, urlQuery url.Values) any {
+	if spec.Style == StyleDeepObject {
+		prefix := spec.Name + "["
+		subset := url.Values{}
+		for key, values := range urlQuery {
+			if strings.HasPrefix(key, prefix) {
+				subset[key] = values
+			}
+		}
+		if len(subset) == 0 {
+			return nil
+		}
+		// The properties are kept as QueryMap even if they are numeric: "color[0]=x"
+		object, _ := NewParser(ParseOptions{DisableIndexNormalization: true}).FromValues(subset)
+		return object[spec.Name]
+	}
+
+	object := newQueryMap()
+	for key, values := range urlQuery {
+		if len(spec.Properties) > 0 && !slices.Contains(spec.Properties, key) ||
+			len(spec.Properties) == 0 && s.describes(key) {
+			continue
+		}
+		if len(values) == 1 {
+			object[key] = values[0]
+		} else {
+			object[key] = slices.Clone(values)
+		}
+	}
+	if len(object) == 0 {
+		return nil
+	}
+
+	return object
+}
+
+// describes reports whether the parameter `key` is described by one of the specs
+// (exploded form objects without Properties don't describe any specific parameter).
+func (s ParamSpecs) describes(key string) bool {
+	for _, spec := range s {
+		switch {
+		case spec.Kind == KindObject && spec.Style == StyleForm && spec.exploded():
+			if slices.Contains(spec.Properties, key) {
+				return true
+			}
+		case spec.Style == StyleDeepObject:
+			if strings.HasPrefix(key, spec.Name+"[") {
+				return true
+			}
+		case spec.Name == key:
+			return true
+		}
+	}
+
+	return false
+}
+
+// ToValues writes the QueryMap into url.Values according to the specs, the reverse of FromValues.
+// The object properties are written in sorted order. The entries not described by the specs are ignored.
+// Returns *SpecError if an entry doesn't match its spec.
+func (s ParamSpecs) ToValues(q QueryMap) (url.Values, error) {
+	values := make(url.Values)
+
+	for _, spec := range s {
+		if err := spec.validate(); err != nil {
+			return nil, err
+		}
+
+		value, ok := q[spec.Name]
+		if !ok || value == nil {
+			continue
+		}
+
+		if err := writeParam(values, spec, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// writeParam writes a single parameter into `values`.
+func writeParam(values url.Values, spec ParamSpec, value any) error {
+	switch spec.Kind {
+	case KindArray:
+		list, ok := paramStrings(value)
+		if !ok {
+			return &SpecError{Name: spec.Name, Reason: fmt.Sprintf("expected a list of primitives, got %T", value)}
+		}
+		if spec.exploded() {
+			values[spec.Name] = append(values[spec.Name], list...)
+		} else {
+			values.Add(spec.Name, strings.Join(list, spec.delimiter()))
+		}
+		return nil
+	case KindObject:
+		object, ok := asQueryMap(value)
+		if !ok {
+			return &SpecError{Name: spec.Name, Reason: fmt.Sprintf("expected an object, got %T", value)}
+		}
+		return writeObjectParam(values, spec, object)
+	}
+
+	list, ok := paramStrings(value)
+	if !ok || len(list) != 1 {
+		return &SpecError{Name: spec.Name, Reason: fmt.Sprintf("expected a primitive, got %T", value)}
+	}
+	values.Add(spec.Name, list[0])
+
+	return nil
+}
+
+// writeObjectParam writes the properties of an object parameter into `values`.
+func writeObjectParam(values url.Values, spec ParamSpec, object QueryMap) error {
+	if spec.Style == StyleDeepObject {
+		for key, v := range (QueryMap{spec.Name: object}).ToValues() {
+			values[key] = append(values[key], v...)
+		}
+		return nil
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	pairs := make([]string, 0, len(object)*2)
+	for _, key := range keys {
+		property, ok := paramStrings(object[key])
+		if !ok {
+			return &SpecError{Name: spec.Name, Reason: fmt.Sprintf("expected primitive properties, got %T", object[key])}
+		}
+
+		if spec.exploded() {
+			values[key] = append(values[key], property...)
+			continue
+		}
+		if len(property) != 1 {
+			return &SpecError{Name: spec.Name, Reason: "expected primitive properties, got a list"}
+		}
+		pairs = append(pairs, key, property[0])
+	}
+
+	if !spec.exploded() {
+		values.Add(spec.Name, strings.Join(pairs, spec.delimiter()))
+	}
+
+	return nil
+}
+
+// paramStrings returns a primitive as a single string, or a list of primitives as strings.
+// Returns false for maps and nested lists.
+func paramStrings(value any) ([]string, bool) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, true
+	case []string:
+		return v, true
+	case anyList:
+		return paramStrings([]any(v))
+	case []any:
+		list := make([]string, len(v))
+		for i, element := range v {
+			s, ok := paramStrings(element)
+			if !ok || len(s) != 1 {
+				return nil, false
+			}
+			list[i] = s[0]
+		}
+		return list, true
+	case QueryMap, map[string]any, nil:
+		return nil, false
+	}
+
+	return []string{fmt.Sprint(value)}, true
+}
+
+// ParamSpecsToStruct is a convenient function that combines ParamSpecs.FromValues and ToStruct.
+func ParamSpecsToStruct[T any](specs ParamSpecs, urlQuery url.Values) (*T, error) {
+	data, err := specs.FromValues(urlQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return ToStruct[T](data)
+}
+
+// StructToParamValues is a convenient function that combines FromStruct and ParamSpecs.ToValues.
+func StructToParamValues[T any](specs ParamSpecs, value T) (url.Values, error) {
+	data, err := FromStruct(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return specs.ToValues(data)
+}
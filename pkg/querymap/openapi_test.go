@@ -0,0 +1,224 @@
+package querymap
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// The examples of the "Style Examples" table of the OpenAPI 3 specification.
+func TestParamSpecsStyleExamples(t *testing.T) {
+	array := []string{"blue", "black", "brown"}
+	object := QueryMap{"B": "150", "G": "200", "R": "100"}
+
+	tests := []struct {
+		name  string
+		spec  ParamSpec
+		value any
+		query string
+	}{
+		{
+			name:  "form primitive",
+			spec:  ParamSpec{Name: "color"},
+			value: "blue",
+			query: "color=blue",
+		},
+		{
+			name:  "form array",
+			spec:  ParamSpec{Name: "color", Kind: KindArray, Explode: NotExploded},
+			value: array,
+			query: "color=blue%2Cblack%2Cbrown",
+		},
+		{
+			name:  "form array exploded",
+			spec:  ParamSpec{Name: "color", Kind: KindArray},
+			value: array,
+			query: "color=blue&color=black&color=brown",
+		},
+		{
+			name:  "form array empty",
+			spec:  ParamSpec{Name: "color", Kind: KindArray, Explode: NotExploded},
+			value: []string{},
+			query: "color=",
+		},
+		{
+			name:  "form object",
+			spec:  ParamSpec{Name: "color", Kind: KindObject, Explode: NotExploded},
+			value: object,
+			query: "color=B%2C150%2CG%2C200%2CR%2C100",
+		},
+		{
+			name:  "form object empty",
+			spec:  ParamSpec{Name: "color", Kind: KindObject, Explode: NotExploded},
+			value: QueryMap{},
+			query: "color=",
+		},
+		{
+			name:  "form object exploded",
+			spec:  ParamSpec{Name: "color", Kind: KindObject, Properties: []string{"R", "G", "B"}},
+			value: object,
+			query: "B=150&G=200&R=100",
+		},
+		{
+			name:  "spaceDelimited array",
+			spec:  ParamSpec{Name: "color", Style: StyleSpaceDelimited, Kind: KindArray},
+			value: array,
+			query: "color=blue+black+brown",
+		},
+		{
+			name:  "spaceDelimited object",
+			spec:  ParamSpec{Name: "color", Style: StyleSpaceDelimited, Kind: KindObject},
+			value: object,
+			query: "color=B+150+G+200+R+100",
+		},
+		{
+			name:  "pipeDelimited array",
+			spec:  ParamSpec{Name: "color", Style: StylePipeDelimited, Kind: KindArray},
+			value: array,
+			query: "color=blue%7Cblack%7Cbrown",
+		},
+		{
+			name:  "pipeDelimited array exploded",
+			spec:  ParamSpec{Name: "color", Style: StylePipeDelimited, Kind: KindArray, Explode: Exploded},
+			value: array,
+			query: "color=blue&color=black&color=brown",
+		},
+		{
+			name:  "deepObject",
+			spec:  ParamSpec{Name: "color", Style: StyleDeepObject, Kind: KindObject},
+			value: object,
+			query: "color%5BB%5D=150&color%5BG%5D=200&color%5BR%5D=100",
+		},
+		{
+			name:  "deepObject numeric properties",
+			spec:  ParamSpec{Name: "color", Style: StyleDeepObject, Kind: KindObject},
+			value: QueryMap{"0": "x", "1": "y"},
+			query: "color%5B0%5D=x&color%5B1%5D=y",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				specs := ParamSpecs{tt.spec}
+
+				values, err := specs.ToValues(QueryMap{"color": tt.value})
+				panicIfErr(err)
+				if got := values.Encode(); got != tt.query {
+					t.Errorf("ToValues() = %v, want %v", got, tt.query)
+				}
+
+				parsed, err := url.ParseQuery(tt.query)
+				panicIfErr(err)
+				got, err := specs.FromValues(parsed)
+				panicIfErr(err)
+				if want := (QueryMap{"color": tt.value}); !reflect.DeepEqual(got, want) {
+					t.Errorf("FromValues() = %v, want %v", got, want)
+				}
+			},
+		)
+	}
+}
+
+func TestParamSpecsExplodedObjectCollectsRest(t *testing.T) {
+	specs := ParamSpecs{
+		{Name: "limit"},
+		{Name: "filter", Kind: KindObject},
+		{Name: "sort", Style: StyleDeepObject, Kind: KindObject},
+	}
+
+	values, err := url.ParseQuery("limit=10&status=open&owner=7&sort[name]=asc")
+	panicIfErr(err)
+
+	got, err := specs.FromValues(values)
+	panicIfErr(err)
+
+	want := QueryMap{
+		"limit":  "10",
+		"filter": QueryMap{"status": "open", "owner": "7"},
+		"sort":   QueryMap{"name": "asc"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FromValues() = %v, want %v", got, want)
+	}
+}
+
+func TestParamSpecsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  ParamSpec
+		query string
+		want  string
+	}{
+		{
+			name: "delimited primitive",
+			spec: ParamSpec{Name: "a", Style: StylePipeDelimited},
+			want: "'a': delimited styles are not defined for primitive values",
+		},
+		{
+			name: "deepObject array",
+			spec: ParamSpec{Name: "a", Style: StyleDeepObject, Kind: KindArray},
+			want: "'a': deepObject style is defined only for exploded objects",
+		},
+		{
+			name:  "repeated primitive",
+			spec:  ParamSpec{Name: "a"},
+			query: "a=1&a=2",
+			want:  "'a': expected a single value",
+		},
+		{
+			name:  "odd object pairs",
+			spec:  ParamSpec{Name: "a", Kind: KindObject, Explode: NotExploded},
+			query: "a=R,100,G",
+			want:  "'a': expected property names and values in pairs",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				values, err := url.ParseQuery(tt.query)
+				panicIfErr(err)
+
+				_, err = ParamSpecs{tt.spec}.FromValues(values)
+
+				var specErr *SpecError
+				if !errors.As(err, &specErr) {
+					t.Fatalf("Expected *SpecError, got %v", err)
+				}
+				if err.Error() != tt.want {
+					t.Errorf("Expected error to be '%s', got '%s'", tt.want, err.Error())
+				}
+			},
+		)
+	}
+}
+
+func TestParamSpecsStruct(t *testing.T) {
+	type Params struct {
+		IDs    []int             `json:"ids"`
+		Colors []string          `json:"colors"`
+		Filter map[string]string `json:"filter"`
+	}
+
+	specs := ParamSpecs{
+		{Name: "ids", Kind: KindArray, Explode: NotExploded},
+		{Name: "colors", Style: StylePipeDelimited, Kind: KindArray},
+		{Name: "filter", Style: StyleDeepObject, Kind: KindObject},
+	}
+
+	params := Params{IDs: []int{1, 2}, Colors: []string{"red", "blue"}, Filter: map[string]string{"status": "open"}}
+
+	values, err := StructToParamValues(specs, params)
+	panicIfErr(err)
+
+	const want = "colors=red%7Cblue&filter%5Bstatus%5D=open&ids=1%2C2"
+	if got := values.Encode(); got != want {
+		t.Errorf("StructToParamValues() = %v, want %v", got, want)
+	}
+
+	got, err := ParamSpecsToStruct[Params](specs, values)
+	panicIfErr(err)
+	if !reflect.DeepEqual(*got, params) {
+		t.Errorf("ParamSpecsToStruct() = %+v, want %+v", *got, params)
+	}
+}
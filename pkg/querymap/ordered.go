@@ -0,0 +1,140 @@
+package querymap
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Param is a single decoded "key=value" pair of a raw query string.
+type Param struct {
+	Key   string
+	Value string
+}
+
+// OrderedQueryMap is a QueryMap that remembers the original order of the query parameters,
+// which url.Values loses: "sort=name&filter=x&sort=-date" keeps "name" before "-date" and "sort" before "filter".
+type OrderedQueryMap struct {
+	QueryMap
+
+	// Params are the decoded pairs in their original order.
+	Params []Param
+
+	// Warnings are the malformed pairs kept by the MalformedWarn mode, see ParseOptions.Malformed.
+	Warnings []*SyntaxError
+
+	keys *keyOrder
+}
+
+// keyOrder records the order in which the keys of a map first appeared in the query, and the orders of its nested maps.
+type keyOrder struct {
+	keys     []string
+	children map[string]*keyOrder
+}
+
+// Keys returns the keys of the map found by the `path` segments ("filter", "owner")
+// in the order they first appeared in the query, the top-level keys if the path is empty.
+// Returns nil if there is no map by the path.
+func (o *OrderedQueryMap) Keys(path ...string) []string {
+	node := o.keys
+	for _, segment := range path {
+		if node == nil {
+			return nil
+		}
+		node = node.children[segment]
+	}
+	if node == nil {
+		return nil
+	}
+
+	return append([]string(nil), node.keys...)
+}
+
+// Encode encodes the Params back into a query string in their original order.
+func (o *OrderedQueryMap) Encode() string {
+	var b strings.Builder
+	for i, param := range o.Params {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(url.QueryEscape(param.Key))
+		b.WriteByte('=')
+		b.WriteString(url.QueryEscape(param.Value))
+	}
+
+	return b.String()
+}
+
+// record adds the key segments of a single parameter to the order.
+func (k *keyOrder) record(segments []string) {
+	for _, segment := range segments {
+		child, ok := k.children[segment]
+		if !ok {
+			child = &keyOrder{children: map[string]*keyOrder{}}
+			k.keys = append(k.keys, segment)
+			k.children[segment] = child
+		}
+		k = child
+	}
+}
+
+// FromRawQuery parses the raw query string ("a[b]=1&c=2", without the leading "?") directly,
+// not through url.Values, and returns an OrderedQueryMap that keeps the original order of the parameters.
+// The malformed pairs are handled according to ParseOptions.Malformed, by default the same way url.ParseQuery does.
+// Returns *SyntaxError for a malformed pair in MalformedError mode,
+// *LimitError if the query exceeds the configured Limits
+// and *IndexError if the indexes are rejected by IndexStrict mode.
+func (p *Parser) FromRawQuery(rawQuery string) (*OrderedQueryMap, error) {
+	params, warnings, err := p.splitRawQuery(rawQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	urlQuery := make(url.Values, len(params))
+	for _, param := range params {
+		urlQuery[param.Key] = append(urlQuery[param.Key], param.Value)
+	}
+
+	data, err := p.FromValues(urlQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OrderedQueryMap{QueryMap: data, Params: params, Warnings: warnings, keys: p.keyOrder(params)}, nil
+}
+
+// keyOrder builds the order of the keys by splitting every parameter key the same way FromValues does.
+func (p *Parser) keyOrder(params []Param) *keyOrder {
+	order := &keyOrder{children: map[string]*keyOrder{}}
+
+	for _, param := range params {
+		key := param.Key
+		switch p.options.Syntax {
+		case FlatSyntax:
+			order.record([]string{key})
+			continue
+		case DotSyntax:
+			key = dotKeyToBrackets(key)
+		}
+
+		segments, _ := splitKey(key)
+		order.record(segments)
+	}
+
+	return order
+}
+
+// FromRawQuery parses the raw query string and returns an OrderedQueryMap
+// that keeps the original order of the parameters, see Parser.FromRawQuery.
+func FromRawQuery(rawQuery string) *OrderedQueryMap {
+	// The default parser has no limits, so it never fails
+	data, _ := NewParser(ParseOptions{}).FromRawQuery(rawQuery)
+
+	return data
+}
+
+// FromString is a convenient function that combines NewParser and Parser.FromRawQuery.
+// Unlike url.URL.Query, it can report the malformed pairs (see ParseOptions.Malformed)
+// and accept ";" as a separator (see ParseOptions.SemicolonSeparator).
+func FromString(rawQuery string, options ParseOptions) (*OrderedQueryMap, error) {
+	return NewParser(options).FromRawQuery(rawQuery)
+}
@@ -0,0 +1,127 @@
+package querymap
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestFromRawQuery(t *testing.T) {
+	const rawQuery = "sort=name&filter[status]=open&sort=-date&filter[owner]=7&page=2&filter[status]=closed"
+
+	got := FromRawQuery(rawQuery)
+
+	wantMap := QueryMap{
+		"sort":   []string{"name", "-date"},
+		"filter": QueryMap{"status": []string{"open", "closed"}, "owner": "7"},
+		"page":   "2",
+	}
+	if !reflect.DeepEqual(got.QueryMap, wantMap) {
+		t.Errorf("FromRawQuery() = %v, want %v", got.QueryMap, wantMap)
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	panicIfErr(err)
+	if want := FromValues(values); !reflect.DeepEqual(got.QueryMap, want) {
+		t.Errorf("FromRawQuery() = %v, want the FromValues result %v", got.QueryMap, want)
+	}
+
+	wantParams := []Param{
+		{Key: "sort", Value: "name"},
+		{Key: "filter[status]", Value: "open"},
+		{Key: "sort", Value: "-date"},
+		{Key: "filter[owner]", Value: "7"},
+		{Key: "page", Value: "2"},
+		{Key: "filter[status]", Value: "closed"},
+	}
+	if !reflect.DeepEqual(got.Params, wantParams) {
+		t.Errorf("FromRawQuery().Params = %v, want %v", got.Params, wantParams)
+	}
+
+	if encoded := got.Encode(); encoded != "sort=name&filter%5Bstatus%5D=open&sort=-date&filter%5Bowner%5D=7&page=2&filter%5Bstatus%5D=closed" {
+		t.Errorf("OrderedQueryMap.Encode() = %s", encoded)
+	}
+}
+
+func TestOrderedQueryMapKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  ParseOptions
+		rawQuery string
+		path     []string
+		want     []string
+	}{
+		{
+			name:     "top-level keys",
+			rawQuery: "z=1&a=2&m=3&a=4",
+			want:     []string{"z", "a", "m"},
+		},
+		{
+			name:     "nested keys",
+			rawQuery: "filter[z]=1&x=2&filter[a][c]=3&filter[a][b]=4",
+			path:     []string{"filter"},
+			want:     []string{"z", "a"},
+		},
+		{
+			name:     "deeply nested keys",
+			rawQuery: "filter[z]=1&x=2&filter[a][c]=3&filter[a][b]=4",
+			path:     []string{"filter", "a"},
+			want:     []string{"c", "b"},
+		},
+		{
+			name:     "list elements",
+			rawQuery: "items[1][name]=x&items[0][price]=1&items[0][name]=y",
+			path:     []string{"items", "0"},
+			want:     []string{"price", "name"},
+		},
+		{
+			name:     "dot syntax",
+			options:  ParseOptions{Syntax: DotSyntax},
+			rawQuery: "f.b=1&f[a]=2",
+			path:     []string{"f"},
+			want:     []string{"b", "a"},
+		},
+		{
+			name:     "flat syntax",
+			options:  ParseOptions{Syntax: FlatSyntax},
+			rawQuery: "f[b]=1&f[a]=2",
+			want:     []string{"f[b]", "f[a]"},
+		},
+		{
+			name:     "unknown path",
+			rawQuery: "a=1",
+			path:     []string{"a", "b"},
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := NewParser(tt.options).FromRawQuery(tt.rawQuery)
+				panicIfErr(err)
+				if keys := got.Keys(tt.path...); !reflect.DeepEqual(keys, tt.want) {
+					t.Errorf("OrderedQueryMap.Keys(%v) = %v, want %v", tt.path, keys, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestParserFromRawQueryMalformed(t *testing.T) {
+	got, err := NewParser(ParseOptions{}).FromRawQuery("a=%zz&b=1&c=2;d=3&&e")
+
+	panicIfErr(err)
+	want := []Param{{Key: "b", Value: "1"}, {Key: "e", Value: ""}}
+	if !reflect.DeepEqual(got.Params, want) {
+		t.Errorf("Parser.FromRawQuery().Params = %v, want %v", got.Params, want)
+	}
+}
+
+func TestParserFromRawQueryLimits(t *testing.T) {
+	_, err := NewParser(ParseOptions{Limits: Limits{MaxParameters: 2}}).FromRawQuery("a=1&a=2&a=3")
+
+	const exceptedMessage = "query exceeds MaxParameters limit of 2"
+	if err == nil || err.Error() != exceptedMessage {
+		t.Errorf("Expected error to be '%s', got '%v'", exceptedMessage, err)
+	}
+}
@@ -0,0 +1,261 @@
+package querymap
+
+import (
+	"golang.org/x/exp/maps"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Syntax defines how query keys are split into nested structures: by brackets, by dots, or not at all (flat).
+type Syntax int
+
+const (
+	// BracketSyntax nests the keys of the form "key[a][b]" (default).
+	BracketSyntax Syntax = iota
+	// FlatSyntax disables nesting, the keys are used as is: "key[a]=1" => QueryMap{"key[a]": "1"}.
+	FlatSyntax
+	// DotSyntax nests the keys of the form "key.a.b", as sent by Spring or ASP.NET clients.
+	// Brackets are accepted as well ("items[0].name"), and "\." is a literal dot: "file\.name" => "file.name".
+	DotSyntax
+)
+
+// ParseOptions configures how a Parser converts query parameters into a QueryMap.
+// The zero value reproduces the behavior of FromValues.
+type ParseOptions struct {
+	// Syntax selects bracket nesting of the keys (default), dot nesting or flat keys.
+	Syntax Syntax
+
+	// DisableIndexNormalization keeps maps with numeric keys as QueryMap
+	// instead of converting them into slices (see NormalizeSlicesNumbersIndexes).
+	DisableIndexNormalization bool
+
+	// IndexMode defines how the numeric keys are converted into slices, see NormalizeIndexes.
+	IndexMode IndexMode
+
+	// AlwaysSlices stores every value as []string, even if the key has a single value.
+	AlwaysSlices bool
+
+	// Limits protects the parser against hostile query strings, the zero value disables all limits.
+	Limits Limits
+
+	// Delimiter splits every value containing it into a list: "tags=a,b" => []string{"a", "b"}.
+	// Zero disables splitting (default). The OpenAPI `form` style with explode=false uses CommaDelimiter,
+	// `pipeDelimited` uses PipeDelimiter and `spaceDelimited` uses SpaceDelimiter.
+	Delimiter rune
+
+	// KeyDelimiters overrides the Delimiter for specific keys, as they are sent ("tags", "filter[ids]").
+	// A zero rune disables splitting of the key.
+	KeyDelimiters map[string]rune
+
+	// Malformed defines how Parser.FromRawQuery handles the malformed pairs of a raw query string.
+	Malformed MalformedMode
+
+	// SemicolonSeparator makes Parser.FromRawQuery split the pairs by ";" as well as by "&".
+	SemicolonSeparator bool
+}
+
+// Delimiters of the list values matching the OpenAPI parameter styles, see ParseOptions.Delimiter.
+const (
+	CommaDelimiter = ','
+	PipeDelimiter  = '|'
+	SpaceDelimiter = ' '
+)
+
+// Parser converts query parameters into a QueryMap according to its ParseOptions.
+// A Parser is safe for concurrent use.
+type Parser struct {
+	options ParseOptions
+}
+
+// NewParser creates and returns a Parser configured by `options`.
+func NewParser(options ParseOptions) *Parser {
+	return &Parser{options: options}
+}
+
+// FromURL parses the *url.URL object and returns a QueryMap representing
+// all its query parameters as a nested structure.
+func (p *Parser) FromURL(URL *url.URL) (QueryMap, error) {
+	return p.FromValues(URL.Query())
+}
+
+// FromValues parses the url.Values object and returns a QueryMap representing
+// all its query parameters as a nested structure.
+// The keys are processed in sorted order in a single pass: every key is split once
+// and its value is inserted directly at its final location.
+// Returns *LimitError if the query exceeds the configured Limits
+// and *IndexError if the indexes are rejected by IndexStrict mode.
+func (p *Parser) FromValues(urlQuery url.Values) (QueryMap, error) {
+	// The limits count the values as they will be split, before any of them is allocated
+	if err := p.checkLimits(urlQuery); err != nil {
+		return nil, err
+	}
+
+	urlQuery = p.splitValues(urlQuery)
+
+	if p.options.Syntax == DotSyntax {
+		urlQuery = dotsToBrackets(urlQuery)
+	}
+
+	data := newQueryMap()
+
+	urlQueryKeys := maps.Keys(urlQuery)
+	// First sort the keys for a predictable order
+	slices.Sort(urlQueryKeys)
+
+	// The top-level keys holding the maps that may be converted into slices
+	var indexed []string
+	// The buffer of the key segments, reused by all the keys
+	var segments []string
+
+	for _, key := range urlQueryKeys {
+		value := urlQuery[key]
+
+		if p.options.Syntax == FlatSyntax {
+			p.flatQuery(data, key, value)
+			continue
+		}
+
+		if err := p.checkKeyLimits(key); err != nil {
+			return nil, err
+		}
+
+		var isList bool
+		segments, isList = appendKeySegments(segments[:0], key)
+		insert(data, segments, p.leafValue(value, isList))
+
+		if hasIndexSegment(segments) {
+			indexed = append(indexed, segments[0])
+		}
+	}
+
+	if p.options.DisableIndexNormalization {
+		return data, nil
+	}
+
+	// Normalize the values holding numeric keys (converting a set of numeric keys to a slice)
+	for _, k := range slices.Compact(indexed) {
+		normalized, err := normalizeIndexes(k, data[k], p.options.IndexMode, p.options.Limits.MaxIndex)
+		if err != nil {
+			return nil, err
+		}
+		data[k] = normalized
+	}
+
+	return data, nil
+}
+
+// leafValue returns the value stored by a key: the list of values for the "key[]" keys,
+// otherwise a single value as string (unless AlwaysSlices is set) and repeated values as []string.
+func (p *Parser) leafValue(value []string, isList bool) any {
+	if len(value) == 1 && !isList && !p.options.AlwaysSlices {
+		return value[0]
+	}
+
+	return value
+}
+
+// splitValues splits the values of the keys by their delimiters (see ParseOptions.Delimiter),
+// the values without a delimiter are kept as is.
+func (p *Parser) splitValues(urlQuery url.Values) url.Values {
+	if p.options.Delimiter == 0 && len(p.options.KeyDelimiters) == 0 {
+		return urlQuery
+	}
+
+	result := make(url.Values, len(urlQuery))
+	for key, value := range urlQuery {
+		delimiter := p.delimiter(key)
+		if delimiter == 0 {
+			result[key] = value
+			continue
+		}
+
+		split := make([]string, 0, len(value))
+		for _, v := range value {
+			split = append(split, strings.Split(v, string(delimiter))...)
+		}
+		result[key] = split
+	}
+
+	return result
+}
+
+// delimiter returns the delimiter splitting the values of the key, zero if they are not split.
+func (p *Parser) delimiter(key string) rune {
+	if keyDelimiter, ok := p.options.KeyDelimiters[key]; ok {
+		return keyDelimiter
+	}
+
+	return p.options.Delimiter
+}
+
+// dotsToBrackets rewrites the keys of the form "key.a.b" into "key[a][b]",
+// so that DotSyntax queries go through the same nesting and limits as BracketSyntax.
+// The values of keys rewritten into the same key ("a.b" and "a[b]") are combined in key order.
+func dotsToBrackets(urlQuery url.Values) url.Values {
+	keys := maps.Keys(urlQuery)
+	slices.Sort(keys)
+
+	result := make(url.Values, len(urlQuery))
+	for _, key := range keys {
+		bracketKey := dotKeyToBrackets(key)
+		result[bracketKey] = append(result[bracketKey], urlQuery[key]...)
+	}
+
+	return result
+}
+
+// dotKeyToBrackets rewrites a single key: "a.b[0].c" => "a[b][0][c]", `a\.b` => "a.b".
+// A dot is a separator only between two non-empty segments outside brackets, otherwise it's literal.
+func dotKeyToBrackets(key string) string {
+	var b strings.Builder
+	b.Grow(len(key) + 2)
+
+	segmentOpen, inBrackets := false, false
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+
+		switch {
+		case c == '\\' && i+1 < len(key) && key[i+1] == '.':
+			b.WriteByte('.')
+			i++
+		case c == '.' && !inBrackets && i > 0 && i+1 < len(key) && key[i+1] != '.' && key[i+1] != '[':
+			if segmentOpen {
+				b.WriteByte(']')
+			}
+			b.WriteByte('[')
+			segmentOpen = true
+		case c == '[' && !inBrackets:
+			if segmentOpen {
+				b.WriteByte(']')
+				segmentOpen = false
+			}
+			inBrackets = true
+			b.WriteByte(c)
+		case c == ']' && inBrackets:
+			inBrackets = false
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if segmentOpen {
+		b.WriteByte(']')
+	}
+
+	return b.String()
+}
+
+// flatQuery sets the value by the key as is, without nesting.
+func (p *Parser) flatQuery(data QueryMap, key string, value []string) QueryMap {
+	if len(value) == 1 && !p.options.AlwaysSlices {
+		return data.set(key, value[0])
+	}
+
+	return data.set(key, value)
+}
+
+// FromValuesWithOptions is a convenient function that combines NewParser and Parser.FromValues.
+func FromValuesWithOptions(urlQuery url.Values, options ParseOptions) (QueryMap, error) {
+	return NewParser(options).FromValues(urlQuery)
+}
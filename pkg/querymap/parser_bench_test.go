@@ -0,0 +1,182 @@
+package querymap
+
+import (
+	"fmt"
+	"golang.org/x/exp/maps"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// legacyFromValues is the two-pass implementation of Parser.FromValues replaced by the single-pass parser,
+// kept as the reference of its behavior.
+func legacyFromValues(urlQuery url.Values, alwaysSlices bool, mode IndexMode) (QueryMap, error) {
+	data := newQueryMap()
+
+	keys := maps.Keys(urlQuery)
+	slices.Sort(keys)
+	for _, key := range keys {
+		legacyNestedQuery(data, key, urlQuery[key], alwaysSlices)
+	}
+
+	for k, v := range data {
+		normalized, err := normalizeIndexes(k, v, mode, 0)
+		if err != nil {
+			return nil, err
+		}
+		data[k] = normalized
+	}
+
+	return data, nil
+}
+
+// legacyNestedQuery is the recursive implementation of the parser replaced by splitKey and insert:
+// it creates a QueryMap per bracket segment and merges it up with QueryMap.set.
+func legacyNestedQuery(data QueryMap, key string, value []string, alwaysSlices bool) QueryMap {
+	nextStart := strings.IndexRune(key, '[')
+	nextEnd := strings.IndexRune(key, ']')
+
+	currentKey := key
+
+	if nextStart == -1 && nextEnd != -1 && len(key) == nextEnd+1 { // key]
+		currentKey = key[:nextEnd]
+	} else if nextStart != -1 && nextEnd != -1 && nextStart+1 == nextEnd { // key[]
+		currentKey = key[:nextStart]
+	} else if nextEnd != -1 && nextEnd+1 == nextStart { // key][
+		currentKey = key[:nextEnd]
+	} else if nextStart != -1 && nextStart < nextEnd { // key[a] or key[]
+		currentKey = key[:nextStart]
+	}
+
+	// If the format is "key[]" or "key][]"
+	//  key[] or key][] and no any text after
+	//  regex: \[\]$ OR regex: \]\[$
+	if nextStart+1 == nextEnd && nextEnd+1 == len(key) || nextEnd != -1 && nextStart > nextEnd && key[nextStart:] == "[]" && nextStart+2 == len(key) {
+		return data.set(currentKey, value)
+	}
+
+	if nextStart != -1 {
+		nextKey := ""
+		if nextEnd != -1 && nextEnd < nextStart { // b][a
+			nextKey = key[nextEnd+2:]
+		} else { // b[]
+			nextKey = key[nextStart+1:]
+		}
+
+		return data.set(currentKey, legacyNestedQuery(newQueryMap(), nextKey, value, alwaysSlices))
+	}
+
+	// If there is only one value, write it as string
+	if len(value) == 1 && !alwaysSlices {
+		return data.set(currentKey, value[0])
+	}
+
+	// Otherwise, we save the slice
+	return data.set(currentKey, value)
+}
+
+func TestParserMatchesLegacyParser(t *testing.T) {
+	queries := []string{
+		"a=1&b=2&b=3",
+		"a[b][c]=1&a[b][d]=2&a[e]=3",
+		"a[]=1&a[]=2&a=3",
+		"a[0]=x&a[1]=y&a[10]=z&a[2]=w",
+		"a[0][b]=1&a[0][c]=2&a[1][b]=3",
+		"a[0]=x&a[]=y",
+		"a=1&a[b]=2&a[c]=3",
+		"a[b]=1&a=2",
+		"a[b]=1&a[b][c]=2",
+		"a[b][c]=1&a[b]=2",
+		"a]=1&a][b]=2&a][]=3&]=4&[]=5&[=6&a[b=7&a]b[c]=8",
+		"a[0]=1&a][1]=2&a[x]=3",
+		"a[-1]=x&a[01]=y&a[+1]=z",
+		"a[[b]]=1&a[]]=2&a[][]=3&a[][b]=4",
+		"x[0][0][0]=1&x[0][1]=2&x[1]=3",
+		"items[2][name]=c&items[0][name]=a&items[1]=b&items[x][0]=d",
+	}
+	for _, mode := range []IndexMode{IndexCompact, IndexPreserve, IndexStrict} {
+		for _, alwaysSlices := range []bool{false, true} {
+			for _, query := range queries {
+				values, err := url.ParseQuery(query)
+				panicIfErr(err)
+
+				want, wantErr := legacyFromValues(values, alwaysSlices, mode)
+				got, err := NewParser(ParseOptions{IndexMode: mode, AlwaysSlices: alwaysSlices}).FromValues(values)
+				if fmt.Sprint(err) != fmt.Sprint(wantErr) || !reflect.DeepEqual(got, want) {
+					t.Errorf(
+						"%s (mode %d, always slices %v): FromValues() = %v, %v, want %v, %v",
+						query, mode, alwaysSlices, got, err, want, wantErr,
+					)
+				}
+			}
+		}
+	}
+}
+
+func TestSplitKey(t *testing.T) {
+	tests := []struct {
+		key      string
+		segments []string
+		isList   bool
+	}{
+		{key: "a", segments: []string{"a"}},
+		{key: "a[b][c]", segments: []string{"a", "b", "c"}},
+		{key: "a[b][]", segments: []string{"a", "b"}, isList: true},
+		{key: "a[][b]", segments: []string{"a", "", "b"}},
+		{key: "a][b]", segments: []string{"a", "b"}},
+		{key: "a][]", segments: []string{"a"}, isList: true},
+		{key: "a[b", segments: []string{"a[b", "b"}},
+		{key: "a]", segments: []string{"a"}},
+		{key: "", segments: []string{""}},
+	}
+	for _, tt := range tests {
+		segments, isList := splitKey(tt.key)
+		if !reflect.DeepEqual(segments, tt.segments) || isList != tt.isList {
+			t.Errorf("splitKey(%q) = %q, %v, want %q, %v", tt.key, segments, isList, tt.segments, tt.isList)
+		}
+	}
+}
+
+// benchmarkQueries returns the queries of the parser benchmarks: flat, nested and indexed keys of `n` parameters.
+func benchmarkQueries(n int) map[string]url.Values {
+	flat, nested, indexed := url.Values{}, url.Values{}, url.Values{}
+	for i := range n {
+		flat.Add(fmt.Sprintf("key%d", i), "value")
+		nested.Add(fmt.Sprintf("filter[group%d][field%d][op]", i%10, i), "value")
+		indexed.Add(fmt.Sprintf("items[%d][name]", i), "value")
+	}
+
+	return map[string]url.Values{"flat": flat, "nested": nested, "indexed": indexed}
+}
+
+func BenchmarkParserFromValues(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		queries := benchmarkQueries(n)
+		for _, name := range []string{"flat", "nested", "indexed"} {
+			values := queries[name]
+			b.Run(
+				fmt.Sprintf("%s/%d/single-pass", name, n), func(b *testing.B) {
+					b.ReportAllocs()
+					parser := NewParser(ParseOptions{})
+					for range b.N {
+						if _, err := parser.FromValues(values); err != nil {
+							b.Fatal(err)
+						}
+					}
+				},
+			)
+			b.Run(
+				fmt.Sprintf("%s/%d/legacy", name, n), func(b *testing.B) {
+					b.ReportAllocs()
+					for range b.N {
+						if _, err := legacyFromValues(values, false, IndexCompact); err != nil {
+							b.Fatal(err)
+						}
+					}
+				},
+			)
+		}
+	}
+}
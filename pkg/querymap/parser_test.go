@@ -0,0 +1,172 @@
+package querymap
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParserFromURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ParseOptions
+		URL     string
+		want    QueryMap
+	}{
+		{
+			name:    "default options",
+			options: ParseOptions{},
+			URL:     "example.com?a[b]=1&c[0]=2&c[1]=3",
+			want:    QueryMap{"a": QueryMap{"b": "1"}, "c": anyList{"2", "3"}},
+		},
+		{
+			name:    "flat syntax",
+			options: ParseOptions{Syntax: FlatSyntax},
+			URL:     "example.com?a[b]=1&a[b]=2&c=3",
+			want:    QueryMap{"a[b]": []string{"1", "2"}, "c": "3"},
+		},
+		{
+			name:    "disable index normalization",
+			options: ParseOptions{DisableIndexNormalization: true},
+			URL:     "example.com?c[0]=2&c[1]=3",
+			want:    QueryMap{"c": QueryMap{"0": "2", "1": "3"}},
+		},
+		{
+			name:    "always slices",
+			options: ParseOptions{AlwaysSlices: true},
+			URL:     "example.com?a[b]=1&c[0]=2&d=3&d=4",
+			want: QueryMap{
+				"a": QueryMap{"b": []string{"1"}},
+				"c": anyList{[]string{"2"}},
+				"d": []string{"3", "4"},
+			},
+		},
+		{
+			name:    "preserve index mode",
+			options: ParseOptions{IndexMode: IndexPreserve},
+			URL:     "example.com?c[0]=2&c[2]=3",
+			want:    QueryMap{"c": anyList{"2", nil, "3"}},
+		},
+		{
+			name:    "dot syntax",
+			options: ParseOptions{Syntax: DotSyntax},
+			URL:     "example.com?filter.status=open&filter.owner.id=7&items[0].name=x&items[1].name=y&file%5C.name=a",
+			want: QueryMap{
+				"filter":    QueryMap{"status": "open", "owner": QueryMap{"id": "7"}},
+				"items":     anyList{QueryMap{"name": "x"}, QueryMap{"name": "y"}},
+				"file.name": "a",
+			},
+		},
+		{
+			name:    "dot syntax combines dots and brackets",
+			options: ParseOptions{Syntax: DotSyntax},
+			URL:     "example.com?a.b=1&a[b]=2&a[c]=3",
+			want:    QueryMap{"a": QueryMap{"b": []string{"1", "2"}, "c": "3"}},
+		},
+		{
+			name:    "comma delimiter",
+			options: ParseOptions{Delimiter: CommaDelimiter},
+			URL:     "example.com?tags=a,b,c&name=Ken&ids[]=1,2&ids[]=3",
+			want:    QueryMap{"tags": []string{"a", "b", "c"}, "name": "Ken", "ids": []string{"1", "2", "3"}},
+		},
+		{
+			name: "key delimiters",
+			options: ParseOptions{
+				Delimiter:     CommaDelimiter,
+				KeyDelimiters: map[string]rune{"pipes": PipeDelimiter, "spaces": SpaceDelimiter, "title": 0},
+			},
+			URL: "example.com?pipes=a|b&spaces=a%20b&title=a,b&tags=a,b",
+			want: QueryMap{
+				"pipes":  []string{"a", "b"},
+				"spaces": []string{"a", "b"},
+				"title":  "a,b",
+				"tags":   []string{"a", "b"},
+			},
+		},
+		{
+			name:    "always slices with flat syntax",
+			options: ParseOptions{Syntax: FlatSyntax, AlwaysSlices: true},
+			URL:     "example.com?a[b]=1",
+			want:    QueryMap{"a[b]": []string{"1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				parsedUrl, err := url.Parse(tt.URL)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := NewParser(tt.options).FromURL(parsedUrl)
+				panicIfErr(err)
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Parser.FromURL() = %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestFromValuesWithOptions(t *testing.T) {
+	values := url.Values{"a[0]": {"1"}}
+
+	want := QueryMap{"a[0]": "1"}
+	got, err := FromValuesWithOptions(values, ParseOptions{Syntax: FlatSyntax})
+	panicIfErr(err)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FromValuesWithOptions() = %v, want %v", got, want)
+	}
+
+	got, err = FromValuesWithOptions(values, ParseOptions{})
+	panicIfErr(err)
+	if want := FromValues(values); !reflect.DeepEqual(got, want) {
+		t.Errorf("FromValuesWithOptions() = %v, want %v", got, want)
+	}
+}
+
+func TestParserIndexModeError(t *testing.T) {
+	values := url.Values{"items[0]": {"a"}, "items[2]": {"b"}}
+
+	_, err := NewParser(ParseOptions{IndexMode: IndexStrict}).FromValues(values)
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	const exceptedErr = "'items[1]': missing index"
+	if err.Error() != exceptedErr {
+		t.Errorf("Expected error to be '%s', got %v", exceptedErr, err)
+	}
+}
+
+func TestDotKeyToBrackets(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "a", want: "a"},
+		{key: "a.b.c", want: "a[b][c]"},
+		{key: "a.b[0].c", want: "a[b][0][c]"},
+		{key: "a[x.y].b", want: "a[x.y][b]"},
+		{key: `a\.b.c`, want: "a.b[c]"},
+		{key: ".a", want: ".a"},
+		{key: "a.", want: "a."},
+		{key: "a..b", want: "a.[b]"},
+		{key: "a.[0]", want: "a.[0]"},
+	}
+	for _, tt := range tests {
+		if got := dotKeyToBrackets(tt.key); got != tt.want {
+			t.Errorf("dotKeyToBrackets(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestParserDotSyntaxLimits(t *testing.T) {
+	values := url.Values{"a.b.c.d": {"1"}}
+
+	_, err := FromValuesWithOptions(values, ParseOptions{Syntax: DotSyntax, Limits: Limits{MaxDepth: 2}})
+
+	const exceptedMessage = "'a[b][c][d]' exceeds MaxDepth limit of 2"
+	if err == nil || err.Error() != exceptedMessage {
+		t.Errorf("Expected error to be '%s', got '%v'", exceptedMessage, err)
+	}
+}
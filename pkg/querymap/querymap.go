@@ -1,11 +1,7 @@
 package querymap
 
 import (
-	"github.com/mitchellh/mapstructure"
-	"golang.org/x/exp/maps"
 	"net/url"
-	"slices"
-	"strconv"
 	"strings"
 )
 
@@ -103,48 +99,99 @@ func (q QueryMap) set(key string, untypedValue any) QueryMap {
 	return q
 }
 
-// nestedQuery - recursively parses the key of the form "key[a][b]" and forms nested structures.
-func nestedQuery(data QueryMap, key string, value []string) QueryMap {
-	nextStart := strings.IndexRune(key, '[')
-	nextEnd := strings.IndexRune(key, ']')
-
-	currentKey := key
-
-	if nextStart == -1 && nextEnd != -1 && len(key) == nextEnd+1 { // key]
-		currentKey = key[:nextEnd]
-	} else if nextStart != -1 && nextEnd != -1 && nextStart+1 == nextEnd { // key[]
-		currentKey = key[:nextStart]
-	} else if nextEnd+1 == nextStart { // key][
-		currentKey = key[:nextEnd]
-	} else if nextStart != -1 && nextStart < nextEnd { // key[a] or key[]
-		currentKey = key[:nextStart]
-	}
+// splitKey splits the key of the form "key[a][b]" into its segments "key", "a", "b" in a single pass.
+// `isList` is set for the keys ending with "[]" ("key[a][]"), whose values are always appended as a list.
+// Malformed keys are split the same way the original recursive parser did: "a][b]" is read as "a[b]",
+// an unclosed "[" opens a segment running to the end of the key.
+func splitKey(key string) (segments []string, isList bool) {
+	return appendKeySegments(make([]string, 0, strings.Count(key, "[")+1), key)
+}
 
-	// If the format is "key[]" or "key][]"
-	//  key[] or key][] and no any text after
-	//  regex: \[\]$ OR regex: \]\[$
-	if nextStart+1 == nextEnd && nextEnd+1 == len(key) || nextEnd != -1 && nextStart > nextEnd && key[nextStart:] == "[]" && nextStart+2 == len(key) {
-		return data.set(currentKey, value)
-	}
+// appendKeySegments appends the segments of the key to `segments`, see splitKey.
+// It lets the parser reuse a single buffer for all the keys.
+func appendKeySegments(segments []string, key string) (_ []string, isList bool) {
+
+	for {
+		nextStart := strings.IndexByte(key, '[')
+		nextEnd := strings.IndexByte(key, ']')
+
+		currentKey := key
+		switch {
+		case nextStart == -1 && nextEnd != -1 && len(key) == nextEnd+1: // key]
+			currentKey = key[:nextEnd]
+		case nextStart != -1 && nextEnd != -1 && nextStart+1 == nextEnd: // key[]
+			currentKey = key[:nextStart]
+		case nextEnd != -1 && nextEnd+1 == nextStart: // key][
+			currentKey = key[:nextEnd]
+		case nextStart != -1 && nextStart < nextEnd: // key[a]
+			currentKey = key[:nextStart]
+		}
+		segments = append(segments, currentKey)
+
+		// "key[]" or "key][]" with no text after
+		if nextStart+1 == nextEnd && nextEnd+1 == len(key) ||
+			nextEnd != -1 && nextStart > nextEnd && key[nextStart:] == "[]" && nextStart+2 == len(key) {
+			return segments, true
+		}
+
+		if nextStart == -1 {
+			return segments, false
+		}
 
-	if nextStart != -1 {
-		nextKey := ""
 		if nextEnd != -1 && nextEnd < nextStart { // b][a
-			nextKey = key[nextEnd+2:]
-		} else { // b[]
-			nextKey = key[nextStart+1:]
+			key = key[nextEnd+2:]
+		} else { // b[a]
+			key = key[nextStart+1:]
+		}
+	}
+}
+
+// insert sets the leaf value by the key segments, descending into (or creating) the nested maps in place.
+// When a segment already holds a value that is not a map, the rest of the chain is merged into it
+// the same way QueryMap.set merges values: "a=1&a[b]=2" => []any{"1", QueryMap{"b": "2"}}.
+func insert(data QueryMap, segments []string, leaf any) {
+	last := len(segments) - 1
+
+	for i, segment := range segments[:last] {
+		existing, ok := data[segment]
+		if !ok {
+			child := newQueryMap()
+			data[segment] = child
+			data = child
+			continue
+		}
+
+		if child, ok := existing.(QueryMap); ok {
+			data = child
+			continue
 		}
 
-		return data.set(currentKey, nestedQuery(newQueryMap(), nextKey, value))
+		// Build the rest of the chain and merge it into the existing value
+		var chain any = leaf
+		for j := last; j > i; j-- {
+			chain = QueryMap{segments[j]: chain}
+		}
+		data.set(segment, chain)
+		return
 	}
 
-	// If there is only one value, write it as string
-	if len(value) == 1 {
-		return data.set(currentKey, value[0])
+	data.set(segments[last], leaf)
+}
+
+// hasIndexSegment reports whether a nested segment may be a numeric index ("0", "-1", "+01"),
+// that is whether the map holding it may be converted into a slice by the index normalization.
+func hasIndexSegment(segments []string) bool {
+	for _, segment := range segments[1:] {
+		digits := strings.TrimLeft(segment, "+-")
+		if len(digits) == 0 || len(segment)-len(digits) > 1 {
+			continue
+		}
+		if strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }) == -1 {
+			return true
+		}
 	}
 
-	// Otherwise, we save the slice
-	return data.set(currentKey, value)
+	return false
 }
 
 // FromURL parses the *url.URL object and returns a QueryMap representing
@@ -156,50 +203,44 @@ func FromURL(URL *url.URL) QueryMap {
 // FromValues parses the url.Values object and returns a QueryMap representing
 // all its query parameters as a nested structure.
 func FromValues(urlQuery url.Values) QueryMap {
-	data := newQueryMap()
-
-	urlQueryKeys := maps.Keys(urlQuery)
-	slices.Sort(urlQueryKeys)
-
-	// First sort the keys for a predictable order
-	for _, key := range urlQueryKeys {
-		value := urlQuery[key]
-
-		nestedQuery(data, key, value)
-	}
-
-	// Normalize the values (converting a set of numeric keys to a slice)
-	for k, v := range data {
-		data[k] = NormalizeSlicesNumbersIndexes(v)
-	}
+	// The default parser has no limits, so it never fails
+	data, _ := NewParser(ParseOptions{}).FromValues(urlQuery)
 
 	return data
 }
 
 // ToStruct converts QueryMap into a structure of type T using mapstructure.
-// The fields of the structure are read by the `json` tag.
+// The fields of the structure are named by the `query` tag, then by the `json` tag (see ToStructWithOptions).
+// Returns *DecodeError pointing to the query parameters that can't be decoded.
 func ToStruct[T any](m QueryMap) (*T, error) {
-	var result T
-
-	config := &mapstructure.DecoderConfig{Metadata: nil, Result: &result, WeaklyTypedInput: true, TagName: "json"}
-	decoder, _ := mapstructure.NewDecoder(config)
-	if err := decoder.Decode(m); err != nil {
-		return nil, err
-	}
-
-	return &result, nil
+	return ToStructWithOptions[T](m, DecodeOptions{})
 }
 
 // FromURLToStruct is a convenient function that combines FromURL and ToStruct.
 // Accepts *url.URL and tries to convert the query string into a T structure.
 func FromURLToStruct[T any](URL *url.URL) (*T, error) {
-	return ToStruct[T](FromURL(URL))
+	return FromValuesToStruct[T](URL.Query())
 }
 
 // FromValuesToStruct is a convenient function that combines FromValues and ToStruct.
 // Accepts url.Values and tries to convert the query string into a T structure.
 func FromValuesToStruct[T any](values url.Values) (*T, error) {
-	return ToStruct[T](FromValues(values))
+	return FromValuesToStructWithOptions[T](values, ParseOptions{}, DecodeOptions{})
+}
+
+// FromValuesToStructWithOptions combines Parser.FromValues configured by `parseOptions`
+// and ToStructWithOptions configured by `decodeOptions`. Unlike decoding the parsed QueryMap,
+// the errors name the list elements by the indexes the client sent rather than the compacted ones:
+// "filters[2][price]" for "filters[0][status]=open&filters[2][price]=abc".
+func FromValuesToStructWithOptions[T any](values url.Values, parseOptions ParseOptions, decodeOptions DecodeOptions) (*T, error) {
+	parser := NewParser(parseOptions)
+
+	m, err := parser.FromValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	return toStruct[T](m, decodeOptions, parser.clientPath(values))
 }
 
 // FromURLStringToStruct is an additional wrapper that parses the URL string,
@@ -214,52 +255,12 @@ func FromURLStringToStruct[T any](URL string) (*T, error) {
 }
 
 // NormalizeSlicesNumbersIndexes recursively checks whether the value is
-// a set of numeric keys, and if so, converts it to a slice (anyList).
+// a set of numeric keys, and if so, converts it to a slice (anyList) ordered by index.
 // For example, QueryMap{"0": "first", "1": "second"} => []any{"first", "second"}.
+// Gaps between indexes are dropped, see NormalizeIndexes for the other modes.
 func NormalizeSlicesNumbersIndexes(v any) any {
-	switch value := v.(type) {
-	case string:
-		return value
-	case []string:
-		return value
-	case QueryMap:
-		total := 0
-		keyAreNumbers := 0
-
-		for key := range value {
-			total++
-			if _, err := strconv.Atoi(key); err == nil {
-				keyAreNumbers++
-			}
-		}
-
-		// If all keys are numbers, sort and turn into a slice
-		if total == keyAreNumbers {
-			slc := anyList{}
-
-			valueKeys := maps.Keys(value)
-			slices.Sort(valueKeys)
-			for _, valueKey := range valueKeys {
-				slc = append(slc, value[valueKey])
-			}
-
-			return slc
-		}
-
-		// Otherwise recursively process nested values
-		for k, v := range value {
-			value[k] = NormalizeSlicesNumbersIndexes(v)
-		}
-
-		return value
-	case anyList:
-		entry := make(anyList, len(value))
-		for i, v := range value {
-			entry[i] = NormalizeSlicesNumbersIndexes(v)
-		}
-		return entry
-	}
+	// The compact mode never fails
+	normalized, _ := NormalizeIndexes(v, IndexCompact, 0)
 
-	// If not one of the above cases, return as is
-	return v
+	return normalized
 }
@@ -0,0 +1,111 @@
+package querymap
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// benchmarkSizes are the numbers of parameters (or elements) of the benchmark inputs.
+var benchmarkSizes = []int{1, 10, 100, 1000}
+
+func BenchmarkFromURL(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		rawQuery := strings.Builder{}
+		for i := range n {
+			if i > 0 {
+				rawQuery.WriteByte('&')
+			}
+			fmt.Fprintf(&rawQuery, "filter[field%d][]=value", i)
+		}
+		URL := &url.URL{RawQuery: rawQuery.String()}
+
+		b.Run(
+			fmt.Sprint(n), func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					_ = FromURL(URL)
+				}
+			},
+		)
+	}
+}
+
+func BenchmarkFromURLDepth(b *testing.B) {
+	for _, depth := range benchmarkSizes {
+		URL := &url.URL{RawQuery: "nestedData" + strings.Repeat("[a]", depth) + "=HelloWorld"}
+
+		b.Run(
+			fmt.Sprint(depth), func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					_ = FromURL(URL)
+				}
+			},
+		)
+	}
+}
+
+func BenchmarkNormalizeIndexes(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		// The indexes are inserted in reverse to make the ordering do some work
+		m := make(QueryMap, n)
+		for i := n - 1; i >= 0; i-- {
+			m[fmt.Sprint(i)] = QueryMap{"name": "value", "tags": QueryMap{"0": "a", "1": "b"}}
+		}
+
+		for name, mode := range map[string]IndexMode{"compact": IndexCompact, "preserve": IndexPreserve} {
+			b.Run(
+				fmt.Sprintf("%s/%d", name, n), func(b *testing.B) {
+					b.ReportAllocs()
+					for range b.N {
+						if _, err := NormalizeIndexes(m, mode, 0); err != nil {
+							b.Fatal(err)
+						}
+					}
+				},
+			)
+		}
+	}
+}
+
+type benchmarkItem struct {
+	ID   int      `query:"id"`
+	Name string   `query:"name"`
+	Tags []string `query:"tags"`
+}
+
+type benchmarkParams struct {
+	Page   int             `query:"page"`
+	Query  string          `query:"q"`
+	IDs    []int64         `query:"ids"`
+	Items  []benchmarkItem `query:"items"`
+	Filter struct {
+		Status string  `query:"status"`
+		Ratio  float64 `query:"ratio"`
+	} `query:"filter"`
+}
+
+func BenchmarkFromValuesToStruct(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		values := url.Values{"page": {"2"}, "q": {"shoes"}, "filter[status]": {"open"}, "filter[ratio]": {"0.5"}}
+		for i := range n {
+			values.Add("ids[]", fmt.Sprint(i))
+			values.Set(fmt.Sprintf("items[%d][id]", i), fmt.Sprint(i))
+			values.Set(fmt.Sprintf("items[%d][name]", i), "name")
+			values["items["+fmt.Sprint(i)+"][tags][]"] = []string{"a", "b"}
+		}
+
+		b.Run(
+			fmt.Sprint(n), func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					if _, err := FromValuesToStruct[benchmarkParams](values); err != nil {
+						b.Fatal(err)
+					}
+				}
+			},
+		)
+	}
+}
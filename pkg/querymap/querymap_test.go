@@ -2,7 +2,7 @@ package querymap
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"github.com/mitchellh/mapstructure"
 	"net/url"
 	"reflect"
@@ -416,51 +416,125 @@ func TestNormalizeSlicesNumbersIndexes(t *testing.T) {
 	}
 }
 
-func TestBenchmarkFromURL(t *testing.T) {
-	t.Run(
-		"benchmark default", func(t *testing.T) {
-			rawUrl := strings.Builder{}
-			rawUrl.WriteString("https://example.com?nestedData=1")
-			URL, err := url.Parse(rawUrl.String())
-			if err != nil {
-				t.Fatal(err)
-			}
-			b := testing.Benchmark(
-				func(b *testing.B) {
-					for i := 0; i < b.N; i++ {
-						_ = FromURL(URL)
-					}
-				},
-			)
-			t.Logf("BenchmarkFromURL: %v", b)
-		},
-	)
+func TestNormalizeIndexes(t *testing.T) {
+	rows := QueryMap{}
+	wantRows := anyList{}
+	for i := 0; i < 12; i++ {
+		rows[strconv.Itoa(i)] = strconv.Itoa(i)
+		wantRows = append(wantRows, strconv.Itoa(i))
+	}
 
-	t.Run(
-		"benchmark nested", func(t *testing.T) {
-			rawUrl := strings.Builder{}
-			rawUrl.WriteString("https://example.com")
-			rawUrl.WriteString("?")
-			rawUrl.WriteString("nestedData")
-			for i := 0; i < 100; i++ {
-				rawUrl.WriteString(fmt.Sprintf("[%d]", i))
-			}
-			rawUrl.WriteString("=")
-			rawUrl.WriteString("HelloWorld")
-			URL, err := url.Parse(rawUrl.String())
-			if err != nil {
-				t.Fatal(err)
-			}
-			b := testing.Benchmark(
-				func(b *testing.B) {
-					for i := 0; i < b.N; i++ {
-						_ = FromURL(URL)
-					}
-				},
-			)
-			t.Logf("BenchmarkFromURL: %v", b)
+	tests := []struct {
+		name     string
+		value    any
+		mode     IndexMode
+		maxIndex int
+		want     any
+		wantErr  error
+	}{
+		{
+			name:  "compact orders 10+ rows numerically",
+			value: rows,
+			mode:  IndexCompact,
+			want:  wantRows,
 		},
-	)
+		{
+			name:  "compact drops gaps",
+			value: QueryMap{"0": "a", "10": "c", "2": "b"},
+			mode:  IndexCompact,
+			want:  anyList{"a", "b", "c"},
+		},
+		{
+			name:  "compact normalizes nested indexes",
+			value: QueryMap{"0": QueryMap{"1": "y", "0": "x"}},
+			mode:  IndexCompact,
+			want:  anyList{anyList{"x", "y"}},
+		},
+		{
+			name:  "preserve fills gaps with nil",
+			value: QueryMap{"0": "a", "2": "b"},
+			mode:  IndexPreserve,
+			want:  anyList{"a", nil, "b"},
+		},
+		{
+			name:  "preserve keeps non-canonical keys as map",
+			value: QueryMap{"0": "a", "01": "b"},
+			mode:  IndexPreserve,
+			want:  QueryMap{"0": "a", "01": "b"},
+		},
+		{
+			name:     "preserve rejects index above max",
+			value:    QueryMap{"100000000": "a"},
+			mode:     IndexPreserve,
+			maxIndex: 10,
+			wantErr:  &LimitError{Limit: "MaxIndex", Key: "100000000", Max: 10},
+		},
+		{
+			name:    "preserve uses default max index",
+			value:   QueryMap{"100000000": "a"},
+			mode:    IndexPreserve,
+			wantErr: &LimitError{Limit: "MaxIndex", Key: "100000000", Max: DefaultMaxIndex},
+		},
+		{
+			name:  "strict accepts dense indexes",
+			value: QueryMap{"1": "b", "0": "a"},
+			mode:  IndexStrict,
+			want:  anyList{"a", "b"},
+		},
+		{
+			name:    "strict rejects sparse indexes",
+			value:   QueryMap{"items": QueryMap{"0": "a", "2": "b"}},
+			mode:    IndexStrict,
+			wantErr: &IndexError{Key: "items", Index: "1", Reason: "missing index"},
+		},
+		{
+			name:    "strict rejects negative indexes",
+			value:   QueryMap{"items": QueryMap{"-1": "a", "0": "b"}},
+			mode:    IndexStrict,
+			wantErr: &IndexError{Key: "items", Index: "-1", Reason: "negative index"},
+		},
+		{
+			name:    "strict rejects non-canonical indexes",
+			value:   QueryMap{"items": QueryMap{"00": "a"}},
+			mode:    IndexStrict,
+			wantErr: &IndexError{Key: "items", Index: "00", Reason: "non-canonical index"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := NormalizeIndexes(tt.value, tt.mode, tt.maxIndex)
+				if tt.wantErr != nil {
+					if !reflect.DeepEqual(err, tt.wantErr) {
+						t.Errorf("NormalizeIndexes() error = %v, want %v", err, tt.wantErr)
+					}
+					return
+				}
+				panicIfErr(err)
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("NormalizeIndexes() = %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestIndexErrorIs(t *testing.T) {
+	_, err := NormalizeIndexes(QueryMap{"1": "a"}, IndexStrict, 0)
+	if !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Expected error to match ErrInvalidIndex, got %v", err)
+	}
+}
+
+func TestFromURLNestedIndexes(t *testing.T) {
+	// Before the index normalization became recursive, "a[0][0]=x" produced {a: [{0: x}]}
+	parsedUrl, err := url.Parse("example.com?a[0][0]=x&a[0][1]=y&b[10]=k&b[2]=c")
+	panicIfErr(err)
+
+	want := QueryMap{"a": anyList{anyList{"x", "y"}}, "b": anyList{"c", "k"}}
+	if got := FromURL(parsedUrl); !reflect.DeepEqual(got, want) {
+		t.Errorf("FromURL() = %v, want %v", got, want)
+	}
 }
 
 func panicIfErr(err error) {
@@ -469,6 +543,23 @@ func panicIfErr(err error) {
 	}
 }
 
+// mustParse parses the raw query string `raw` with the options, failing the test on error.
+func mustParse(t *testing.T, raw string, opts ParseOptions) QueryMap {
+	t.Helper()
+
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := NewParser(opts).FromValues(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
 type TestReadMeT1 struct {
 	Names []string `json:"names,omitempty"`
 }
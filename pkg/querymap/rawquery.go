@@ -0,0 +1,208 @@
+package querymap
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrMalformedQuery is matched (via errors.Is) by every *SyntaxError.
+var ErrMalformedQuery = errors.New("malformed query")
+
+// MalformedMode defines how Parser.FromRawQuery handles the malformed pairs of a raw query string:
+// invalid percent-encoding ("a=%zz"), invalid UTF-8 ("a=%ff"), malformed brackets ("a[b=1", "a[b]c=1")
+// and ";" when it's not a separator.
+type MalformedMode int
+
+const (
+	// MalformedIgnore handles the malformed pairs the same way url.ParseQuery does (default):
+	// the pairs with invalid percent-encoding or ";" are dropped, the others are kept as they are.
+	MalformedIgnore MalformedMode = iota
+
+	// MalformedError rejects the query with a *SyntaxError for the first malformed pair.
+	MalformedError
+
+	// MalformedWarn keeps every malformed pair and reports it in OrderedQueryMap.Warnings.
+	// The invalid "%" sequences are kept undecoded and ";" is kept as a part of the pair.
+	MalformedWarn
+)
+
+// SyntaxError describes a malformed pair of a raw query string.
+type SyntaxError struct {
+	// Pair is the raw (undecoded) pair, for example "a=%zz".
+	Pair string
+
+	// Offset is the byte offset of the pair in the raw query string.
+	Offset int
+
+	// Reason describes what is wrong with the pair.
+	Reason string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("malformed pair '%s' at offset %d: %s", e.Pair, e.Offset, e.Reason)
+}
+
+// Unwrap allows matching the error with errors.Is(err, ErrMalformedQuery).
+func (e *SyntaxError) Unwrap() error {
+	return ErrMalformedQuery
+}
+
+// The reasons of SyntaxError.
+const (
+	reasonPercentEncoding = "invalid percent-encoding"
+	reasonUTF8            = "invalid UTF-8"
+	reasonBrackets        = "malformed brackets"
+	reasonSemicolon       = "semicolon is not a separator"
+)
+
+// splitRawQuery splits the raw query string into the decoded pairs in their original order,
+// handling the malformed ones according to ParseOptions.Malformed.
+func (p *Parser) splitRawQuery(rawQuery string) ([]Param, []*SyntaxError, error) {
+	var params []Param
+	var warnings []*SyntaxError
+
+	separators := "&"
+	if p.options.SemicolonSeparator {
+		separators = "&;"
+	}
+
+	for offset := 0; offset <= len(rawQuery); {
+		end := strings.IndexAny(rawQuery[offset:], separators)
+		if end == -1 {
+			end = len(rawQuery)
+		} else {
+			end += offset
+		}
+		pair := rawQuery[offset:end]
+		pairOffset := offset
+		offset = end + 1
+
+		if pair == "" {
+			continue
+		}
+
+		param, reason := p.decodePair(pair)
+		if reason == "" {
+			params = append(params, param)
+			continue
+		}
+
+		syntaxErr := &SyntaxError{Pair: pair, Offset: pairOffset, Reason: reason}
+		switch p.options.Malformed {
+		case MalformedError:
+			return nil, nil, syntaxErr
+		case MalformedWarn:
+			params = append(params, param)
+			warnings = append(warnings, syntaxErr)
+		default:
+			// url.ParseQuery drops only the pairs it can't decode
+			if reason != reasonPercentEncoding && reason != reasonSemicolon {
+				params = append(params, param)
+			}
+		}
+	}
+
+	return params, warnings, nil
+}
+
+// decodePair decodes a single "key=value" pair, the invalid "%" sequences are kept undecoded.
+// Returns the reason if the pair is malformed, empty otherwise.
+func (p *Parser) decodePair(pair string) (Param, string) {
+	rawKey, rawValue, _ := strings.Cut(pair, "=")
+
+	key, keyReason := decodeComponent(rawKey)
+	value, valueReason := decodeComponent(rawValue)
+	param := Param{Key: key, Value: value}
+
+	// The reasons url.ParseQuery drops the pair for come first, see Parser.splitRawQuery
+	switch {
+	case strings.Contains(pair, ";"):
+		return param, reasonSemicolon
+	case keyReason == reasonPercentEncoding || valueReason == reasonPercentEncoding:
+		return param, reasonPercentEncoding
+	case keyReason != "":
+		return param, keyReason
+	case valueReason != "":
+		return param, valueReason
+	case !p.validKey(key):
+		return param, reasonBrackets
+	}
+
+	return param, ""
+}
+
+// validKey reports whether the brackets of the key are well-formed in the Syntax of the parser,
+// a DotSyntax key is checked once its dots are rewritten into brackets: "items[0].name" => "items[0][name]".
+func (p *Parser) validKey(key string) bool {
+	switch p.options.Syntax {
+	case FlatSyntax:
+		return true
+	case DotSyntax:
+		return validBrackets(dotKeyToBrackets(key))
+	}
+
+	return validBrackets(key)
+}
+
+// decodeComponent decodes the query component as url.QueryUnescape does ("+" is a space),
+// keeping the invalid "%" sequences as they are. Returns the reason if the component is malformed.
+func decodeComponent(s string) (string, string) {
+	if !strings.ContainsAny(s, "%+") {
+		if !utf8.ValidString(s) {
+			return s, reasonUTF8
+		}
+		return s, ""
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+
+	reason := ""
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '+':
+			b.WriteByte(' ')
+		case s[i] == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			value, _ := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			b.WriteByte(byte(value))
+			i += 2
+		case s[i] == '%':
+			reason = reasonPercentEncoding
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	decoded := b.String()
+	if reason == "" && !utf8.ValidString(decoded) {
+		reason = reasonUTF8
+	}
+
+	return decoded, reason
+}
+
+// validBrackets reports whether the key is a non-empty name followed by "[segment]"s
+// without nested, unclosed or unopened brackets and without text after them: "a", "a[b][]", but not "a[b", "a[b]c" or "[a]".
+func validBrackets(key string) bool {
+	start := strings.IndexByte(key, '[')
+	if start == -1 {
+		return !strings.Contains(key, "]")
+	}
+	if start == 0 || strings.Contains(key[:start], "]") {
+		return false
+	}
+
+	for rest := key[start:]; rest != ""; {
+		end := strings.IndexByte(rest, ']')
+		if rest[0] != '[' || end == -1 || strings.IndexByte(rest[1:end], '[') != -1 {
+			return false
+		}
+		rest = rest[end+1:]
+	}
+
+	return true
+}
@@ -0,0 +1,157 @@
+package querymap
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestFromString(t *testing.T) {
+	tests := []struct {
+		name         string
+		options      ParseOptions
+		rawQuery     string
+		want         QueryMap
+		wantWarnings []*SyntaxError
+	}{
+		{
+			name:     "ignore malformed pairs as url.ParseQuery does",
+			rawQuery: "a=%zz&b=%ff&c[d=1&e=2;f=3&g=4",
+			want:     QueryMap{"b": "\xff", "c[d": QueryMap{"d": "1"}, "g": "4"},
+		},
+		{
+			name:     "semicolon separator",
+			options:  ParseOptions{SemicolonSeparator: true},
+			rawQuery: "a=1;b[c]=2&d=3",
+			want:     QueryMap{"a": "1", "b": QueryMap{"c": "2"}, "d": "3"},
+		},
+		{
+			name:     "warn about malformed pairs",
+			options:  ParseOptions{Malformed: MalformedWarn},
+			rawQuery: "a=100%&b=%ff&c[d]e=1&f=2;g=3&h=%41",
+			want:     QueryMap{"a": "100%", "b": "\xff", "c": QueryMap{"d]e": "1"}, "f": "2;g=3", "h": "A"},
+			wantWarnings: []*SyntaxError{
+				{Pair: "a=100%", Offset: 0, Reason: "invalid percent-encoding"},
+				{Pair: "b=%ff", Offset: 7, Reason: "invalid UTF-8"},
+				{Pair: "c[d]e=1", Offset: 13, Reason: "malformed brackets"},
+				{Pair: "f=2;g=3", Offset: 21, Reason: "semicolon is not a separator"},
+			},
+		},
+		{
+			name:     "flat syntax doesn't check brackets",
+			options:  ParseOptions{Syntax: FlatSyntax, Malformed: MalformedError},
+			rawQuery: "a[b=1&c]=2",
+			want:     QueryMap{"a[b": "1", "c]": "2"},
+		},
+		{
+			name:     "valid query",
+			options:  ParseOptions{Malformed: MalformedError},
+			rawQuery: "a[b][]=1&a[b][]=2&c=%D0%BF%D1%80%D0%B8+x",
+			want:     QueryMap{"a": QueryMap{"b": []string{"1", "2"}}, "c": "при x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := FromString(tt.rawQuery, tt.options)
+				panicIfErr(err)
+				if !reflect.DeepEqual(got.QueryMap, tt.want) {
+					t.Errorf("FromString() = %#v, want %#v", got.QueryMap, tt.want)
+				}
+				if !reflect.DeepEqual(got.Warnings, tt.wantWarnings) {
+					t.Errorf("FromString().Warnings = %v, want %v", got.Warnings, tt.wantWarnings)
+				}
+			},
+		)
+	}
+}
+
+func TestFromStringIgnoreMatchesParseQuery(t *testing.T) {
+	const rawQuery = "a=%zz&b=%ff&c[d=1&e=2;f=3&g=4&&h&i=%2"
+
+	values, _ := url.ParseQuery(rawQuery)
+	want := FromValues(values)
+
+	got, err := FromString(rawQuery, ParseOptions{})
+	panicIfErr(err)
+	if !reflect.DeepEqual(got.QueryMap, want) {
+		t.Errorf("FromString() = %#v, want the url.ParseQuery result %#v", got.QueryMap, want)
+	}
+}
+
+func TestFromStringErrors(t *testing.T) {
+	tests := []struct {
+		rawQuery string
+		want     string
+	}{
+		{rawQuery: "a=1&b=%zz", want: "malformed pair 'b=%zz' at offset 4: invalid percent-encoding"},
+		{rawQuery: "a%=1", want: "malformed pair 'a%=1' at offset 0: invalid percent-encoding"},
+		{rawQuery: "a%ff=%zz", want: "malformed pair 'a%ff=%zz' at offset 0: invalid percent-encoding"},
+		{rawQuery: "a=%C3%28", want: "malformed pair 'a=%C3%28' at offset 0: invalid UTF-8"},
+		{rawQuery: "a[b=1", want: "malformed pair 'a[b=1' at offset 0: malformed brackets"},
+		{rawQuery: "a]=1", want: "malformed pair 'a]=1' at offset 0: malformed brackets"},
+		{rawQuery: "[a]=1", want: "malformed pair '[a]=1' at offset 0: malformed brackets"},
+		{rawQuery: "a[b[c]]=1", want: "malformed pair 'a[b[c]]=1' at offset 0: malformed brackets"},
+		{rawQuery: "x=1&a=1;b=2", want: "malformed pair 'a=1;b=2' at offset 4: semicolon is not a separator"},
+	}
+	for _, tt := range tests {
+		_, err := FromString(tt.rawQuery, ParseOptions{Malformed: MalformedError})
+
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) || !errors.Is(err, ErrMalformedQuery) {
+			t.Errorf("FromString(%q) error = %v, want *SyntaxError", tt.rawQuery, err)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("FromString(%q) error = '%v', want '%s'", tt.rawQuery, err, tt.want)
+		}
+	}
+}
+
+func TestFromStringDotSyntaxMalformed(t *testing.T) {
+	const rawQuery = "items[0].name=x&filter.price.min=1&file\\.name=a"
+	want := QueryMap{
+		"items":     anyList{QueryMap{"name": "x"}},
+		"filter":    QueryMap{"price": QueryMap{"min": "1"}},
+		"file.name": "a",
+	}
+
+	got, err := FromString(rawQuery, ParseOptions{Syntax: DotSyntax, Malformed: MalformedError})
+	panicIfErr(err)
+	if !reflect.DeepEqual(got.QueryMap, want) {
+		t.Errorf("FromString() = %v, want %v", got.QueryMap, want)
+	}
+
+	got, err = FromString(rawQuery, ParseOptions{Syntax: DotSyntax, Malformed: MalformedWarn})
+	panicIfErr(err)
+	if len(got.Warnings) != 0 {
+		t.Errorf("FromString() warnings = %v, want none", got.Warnings)
+	}
+
+	const exceptedErr = "malformed pair 'items[0.name=x' at offset 0: malformed brackets"
+	if _, err := FromString("items[0.name=x", ParseOptions{Syntax: DotSyntax, Malformed: MalformedError}); err == nil || err.Error() != exceptedErr {
+		t.Errorf("Expected error to be '%s', got %v", exceptedErr, err)
+	}
+}
+
+func TestValidBrackets(t *testing.T) {
+	tests := map[string]bool{
+		"a":        true,
+		"a[b]":     true,
+		"a[b][]":   true,
+		"a[][c]":   true,
+		"":         true,
+		"a[b":      false,
+		"a]":       false,
+		"a[b]c":    false,
+		"[a]":      false,
+		"a[b[c]]":  false,
+		"a[b]][c]": false,
+	}
+	for key, want := range tests {
+		if got := validBrackets(key); got != want {
+			t.Errorf("validBrackets(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package querymap
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidSignature is matched (via errors.Is) by every *SignatureError.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// ErrSignatureExpired is matched (via errors.Is) by the *SignatureError of an expired signature.
+var ErrSignatureExpired = errors.New("signature expired")
+
+// SignatureError is returned by Signer.Verify when the signature of a query is missing, wrong or expired.
+type SignatureError struct {
+	// Reason describes why the signature is rejected.
+	Reason string
+
+	// Expired is set if the signature is valid but has expired.
+	Expired bool
+}
+
+func (e *SignatureError) Error() string {
+	return "invalid signature: " + e.Reason
+}
+
+// Unwrap allows matching the error with errors.Is(err, ErrInvalidSignature),
+// and with errors.Is(err, ErrSignatureExpired) if the signature has expired.
+func (e *SignatureError) Unwrap() []error {
+	if e.Expired {
+		return []error{ErrInvalidSignature, ErrSignatureExpired}
+	}
+
+	return []error{ErrInvalidSignature}
+}
+
+// SignerOptions configures a Signer, the zero value signs with HMAC-SHA256
+// into the "signature" parameter and reads the expiry time from the "expires" parameter.
+type SignerOptions struct {
+	// Hash is the hash function of the HMAC, sha256.New by default.
+	Hash func() hash.Hash
+
+	// SignatureKey is the parameter holding the signature, "signature" by default.
+	SignatureKey string
+
+	// ExpiresKey is the parameter holding the expiry time in Unix seconds, "expires" by default.
+	ExpiresKey string
+
+	// Exclude lists the dot-separated paths (see QueryMap.Get) of the parameters that are not signed,
+	// like the tracking parameters added by proxies: "utm_source", "meta.trace".
+	Exclude []string
+
+	// Now returns the current time to check the expiry against, time.Now by default.
+	Now func() time.Time
+}
+
+// Signer signs query parameters with an HMAC of their canonical form (see QueryMap.Canonical)
+// and verifies the signed ones, so a signature survives reordering and re-encoding of the query by proxies.
+// A Signer is safe for concurrent use.
+type Signer struct {
+	key     []byte
+	options SignerOptions
+}
+
+// NewSigner creates and returns a Signer with the secret `key` configured by `options`.
+func NewSigner(key []byte, options SignerOptions) *Signer {
+	if options.Hash == nil {
+		options.Hash = sha256.New
+	}
+	if options.SignatureKey == "" {
+		options.SignatureKey = "signature"
+	}
+	if options.ExpiresKey == "" {
+		options.ExpiresKey = "expires"
+	}
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+
+	return &Signer{key: key, options: options}
+}
+
+// Sign returns a copy of the QueryMap with the expiry time and the signature parameters set.
+// A zero `expires` makes a signature that never expires.
+func (s *Signer) Sign(q QueryMap, expires time.Time) QueryMap {
+	signed := q.Clone()
+	if signed == nil {
+		signed = newQueryMap()
+	}
+
+	delete(signed, s.options.ExpiresKey)
+	if !expires.IsZero() {
+		signed[s.options.ExpiresKey] = strconv.FormatInt(expires.Unix(), 10)
+	}
+	signed[s.options.SignatureKey] = s.signature(signed)
+
+	return signed
+}
+
+// SignURL returns a copy of the URL with its query parameters signed, see Sign.
+func (s *Signer) SignURL(URL *url.URL, expires time.Time) *url.URL {
+	signed := *URL
+	signed.RawQuery = s.Sign(FromValues(URL.Query()), expires).Encode()
+
+	return &signed
+}
+
+// Verify checks the signature and the expiry time of the query parameters of the URL.
+// Returns *SignatureError if the signature is missing, doesn't match the parameters or has expired.
+func (s *Signer) Verify(URL *url.URL) error {
+	return s.VerifyQueryMap(FromValues(URL.Query()))
+}
+
+// VerifyQueryMap checks the signature and the expiry time of the query parameters, see Verify.
+func (s *Signer) VerifyQueryMap(q QueryMap) error {
+	signature, ok := q[s.options.SignatureKey].(string)
+	if !ok {
+		return &SignatureError{Reason: fmt.Sprintf("'%s' must be a single value", s.options.SignatureKey)}
+	}
+
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, s.sum(q)) {
+		return &SignatureError{Reason: "signature mismatch"}
+	}
+
+	expiresValue, ok := q[s.options.ExpiresKey]
+	if !ok {
+		return nil
+	}
+
+	expiresStr, _ := expiresValue.(string)
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil {
+		return &SignatureError{Reason: fmt.Sprintf("'%s' must be a Unix time", s.options.ExpiresKey)}
+	}
+	if !s.options.Now().Before(time.Unix(expires, 0)) {
+		return &SignatureError{Reason: fmt.Sprintf("expired at %s", time.Unix(expires, 0).UTC().Format(time.RFC3339)), Expired: true}
+	}
+
+	return nil
+}
+
+// signature returns the encoded signature of the query parameters.
+func (s *Signer) signature(q QueryMap) string {
+	return base64.RawURLEncoding.EncodeToString(s.sum(q))
+}
+
+// sum returns the HMAC of the canonical form of the query parameters without the signature and the excluded ones.
+func (s *Signer) sum(q QueryMap) []byte {
+	signed := q.Clone()
+	delete(signed, s.options.SignatureKey)
+	for _, path := range s.options.Exclude {
+		signed.Delete(path)
+	}
+
+	mac := hmac.New(s.options.Hash, s.key)
+	mac.Write([]byte(signed.Canonical()))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,144 @@
+package querymap
+
+import (
+	"crypto/sha512"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func signerTestNow() time.Time {
+	return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+}
+
+func TestSignerVerify(t *testing.T) {
+	signer := NewSigner([]byte("secret"), SignerOptions{Exclude: []string{"utm_source", "meta.trace"}, Now: signerTestNow})
+
+	URL, err := url.Parse("https://example.com/download?file=report.pdf&meta[user][id]=7&meta[tags][]=a&meta[tags][]=b")
+	panicIfErr(err)
+	signed := signer.SignURL(URL, signerTestNow().Add(time.Hour))
+
+	if signed == URL || URL.RawQuery != "file=report.pdf&meta[user][id]=7&meta[tags][]=a&meta[tags][]=b" {
+		t.Fatalf("SignURL() modified the URL: %s", URL)
+	}
+	if err := signer.Verify(signed); err != nil {
+		t.Fatalf("Verify(%s) error = %v", signed, err)
+	}
+
+	// A proxy reorders, re-encodes and reindexes the parameters and adds tracking ones
+	values := signed.Query()
+	reencoded := "expires=" + values.Get("expires") + "&signature=" + values.Get("signature") +
+		"&meta%5Btags%5D%5B1%5D=b&meta%5Btags%5D%5B0%5D=a&meta%5Buser%5D%5Bid%5D=7&file=report%2Epdf" +
+		"&utm_source=mail&meta[trace]=abc"
+	proxied := &url.URL{Scheme: "https", Host: "example.com", Path: "/download", RawQuery: reencoded}
+	if err := signer.Verify(proxied); err != nil {
+		t.Errorf("Verify(%s) error = %v", proxied, err)
+	}
+}
+
+func TestSignerVerifyErrors(t *testing.T) {
+	signer := NewSigner([]byte("secret"), SignerOptions{Now: signerTestNow})
+	signed := signer.Sign(QueryMap{"file": "report.pdf", "meta": QueryMap{"user": "7"}}, signerTestNow().Add(time.Minute))
+
+	tests := []struct {
+		name    string
+		modify  func(q QueryMap)
+		signer  *Signer
+		want    string
+		expired bool
+	}{
+		{
+			name:   "missing signature",
+			modify: func(q QueryMap) { delete(q, "signature") },
+			want:   "invalid signature: 'signature' must be a single value",
+		},
+		{
+			name:   "repeated signature",
+			modify: func(q QueryMap) { q["signature"] = []string{q["signature"].(string), "x"} },
+			want:   "invalid signature: 'signature' must be a single value",
+		},
+		{
+			name:   "changed parameter",
+			modify: func(q QueryMap) { q["meta"] = QueryMap{"user": "8"} },
+			want:   "invalid signature: signature mismatch",
+		},
+		{
+			name:   "added parameter",
+			modify: func(q QueryMap) { q["admin"] = "1" },
+			want:   "invalid signature: signature mismatch",
+		},
+		{
+			name:   "extended expiry",
+			modify: func(q QueryMap) { q["expires"] = "99999999999" },
+			want:   "invalid signature: signature mismatch",
+		},
+		{
+			name:   "malformed signature",
+			modify: func(q QueryMap) { q["signature"] = "%%%" },
+			want:   "invalid signature: signature mismatch",
+		},
+		{
+			name:   "other key",
+			signer: NewSigner([]byte("other"), SignerOptions{Now: signerTestNow}),
+			want:   "invalid signature: signature mismatch",
+		},
+		{
+			name:   "other algorithm",
+			signer: NewSigner([]byte("secret"), SignerOptions{Hash: sha512.New, Now: signerTestNow}),
+			want:   "invalid signature: signature mismatch",
+		},
+		{
+			name:    "expired",
+			signer:  NewSigner([]byte("secret"), SignerOptions{Now: func() time.Time { return signerTestNow().Add(time.Minute) }}),
+			want:    "invalid signature: expired at 2024-05-01T12:01:00Z",
+			expired: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				q := signed.Clone()
+				if tt.modify != nil {
+					tt.modify(q)
+				}
+				verifier := signer
+				if tt.signer != nil {
+					verifier = tt.signer
+				}
+
+				err := verifier.VerifyQueryMap(q)
+				var signatureErr *SignatureError
+				if !errors.As(err, &signatureErr) || !errors.Is(err, ErrInvalidSignature) || err.Error() != tt.want {
+					t.Fatalf("Expected error to be '%s', got '%v'", tt.want, err)
+				}
+				if errors.Is(err, ErrSignatureExpired) != tt.expired {
+					t.Errorf("errors.Is(err, ErrSignatureExpired) = %v, want %v", !tt.expired, tt.expired)
+				}
+			},
+		)
+	}
+}
+
+func TestSignerOptions(t *testing.T) {
+	signer := NewSigner([]byte("secret"), SignerOptions{SignatureKey: "sig", ExpiresKey: "exp", Hash: sha512.New})
+
+	signed := signer.Sign(QueryMap{"a": "1", "exp": "1"}, time.Time{})
+	if _, ok := signed["exp"]; ok {
+		t.Errorf("Sign() without expiry kept the expiry parameter: %v", signed)
+	}
+	if sig, ok := signed["sig"].(string); !ok || len(sig) != 86 {
+		t.Errorf("Sign() signature = %v, want a base64 HMAC-SHA512", signed["sig"])
+	}
+	if err := signer.VerifyQueryMap(signed); err != nil {
+		t.Errorf("VerifyQueryMap() error = %v", err)
+	}
+
+	// A correctly signed but malformed expiry
+	signed["exp"] = "soon"
+	signed["sig"] = signer.signature(signed)
+	if err := signer.VerifyQueryMap(signed); err == nil || !strings.Contains(err.Error(), "'exp' must be a Unix time") {
+		t.Errorf("VerifyQueryMap() error = %v, want 'exp' must be a Unix time", err)
+	}
+}
@@ -0,0 +1,444 @@
+package querymap
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// queryField describes how a structure field is named in the query and by the decoder.
+//
+// The field is named in the query by the `query` tag, then by the `json` tag, then by its Go name.
+// The decoder (mapstructure) names it by the `json` tag or the Go name,
+// so the query is translated from the former names to the latter before decoding.
+type queryField struct {
+	field reflect.StructField
+
+	// name is the name of the field in the query.
+	name string
+
+	// path is the name split into the segments of the query: "page[size]" => "page", "size".
+	path []string
+
+	// key is the name of the field for the decoder.
+	key string
+
+	// opts are the options of the `query` tag, or of the `json` tag if there is no `query` tag.
+	opts tagOptions
+
+	// inline means the fields of the structure are read from the parent level of the query:
+	// `query:",inline"`, `query:",squash"`, `query:",explode"` or `json:",squash"`.
+	inline bool
+
+	// squash means the decoder reads the fields of the structure from the parent level (`json:",squash"`).
+	squash bool
+}
+
+// structFields returns the fields of the structure type `t` that can be read from the query.
+func structFields(t reflect.Type) []queryField {
+	fields := make([]queryField, 0, t.NumField())
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		jsonTag := field.Tag.Get("json")
+		queryTag, hasQueryTag := field.Tag.Lookup("query")
+		if hasQueryTag && queryTag == "-" || !hasQueryTag && jsonTag == "-" {
+			continue
+		}
+
+		jsonName, jsonOpts := parseTag(jsonTag)
+		queryName, queryOpts := parseTag(queryTag)
+
+		f := queryField{field: field, name: queryName, key: jsonName, opts: jsonOpts}
+		if f.key == "" {
+			f.key = field.Name
+		}
+		if f.name == "" {
+			f.name = f.key
+		}
+		f.path, _ = splitKey(f.name)
+		if hasQueryTag {
+			f.opts = queryOpts
+		}
+
+		if isStructType(field.Type) {
+			f.squash = jsonOpts.Contains("squash")
+			f.inline = f.squash && !hasQueryTag ||
+				f.opts.Contains("inline") || f.opts.Contains("squash") || f.opts.Contains("explode")
+		}
+
+		if !field.IsExported() && !f.squash {
+			continue
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields
+}
+
+// isStructType reports whether the type is a structure or a pointer to a structure.
+func isStructType(t reflect.Type) bool {
+	return derefType(t).Kind() == reflect.Struct
+}
+
+// derefType returns the type the pointer type `t` points to, or `t` itself.
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+// queryPreparer translates a QueryMap from the query names into the decoder names
+// of the structure fields and applies the options of the `query` tag:
+// the absent keys of fields tagged with `default=value` are set to the value,
+// the absent keys of fields tagged with `required` are reported as errors,
+// and the values of list fields tagged with a delimiter (`comma`, `pipe`, `space`, `delimiter=x`) are split.
+// The QueryMap is copied along the way, the original is left intact.
+type queryPreparer struct {
+	converters  []Converter
+	errorUnused bool
+
+	// path names the parameters of the errors.
+	path pathFunc
+
+	// errs are the errors found along the way, their paths use the query names.
+	errs []*FieldError
+}
+
+// prepareQuery prepares `m` for decoding into the type `t`, see queryPreparer.
+// The parameters of the errors are named by `path`.
+func prepareQuery(m QueryMap, t reflect.Type, options DecodeOptions, path pathFunc) (QueryMap, []*FieldError) {
+	p := &queryPreparer{converters: options.Converters, errorUnused: options.ErrorUnused, path: path}
+
+	result := p.value(m, t, "", nil)
+	if data, ok := result.(QueryMap); ok {
+		return data, p.errs
+	}
+
+	return m, p.errs
+}
+
+// value prepares the value `v` of type `t` found by the `path` segments.
+func (p *queryPreparer) value(v any, t reflect.Type, opts tagOptions, path []string) any {
+	t = derefType(t)
+
+	if delimiter, ok := tagDelimiter(opts); ok && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		switch value := v.(type) {
+		case string:
+			return strings.Split(value, delimiter)
+		case []string:
+			var list []string
+			for _, s := range value {
+				list = append(list, strings.Split(s, delimiter)...)
+			}
+			return list
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if p.isLeafStruct(t) {
+			return v
+		}
+
+		data, ok := asQueryMap(v)
+		if !ok {
+			return v
+		}
+
+		return p.structure(data, t, path)
+	case reflect.Slice, reflect.Array:
+		var list anyList
+		switch value := v.(type) {
+		case anyList:
+			list = slices.Clone(value)
+		case []any:
+			list = slices.Clone(anyList(value))
+		default:
+			return v
+		}
+
+		for i, elem := range list {
+			list[i] = p.value(elem, t.Elem(), "", appendPath(path, strconv.Itoa(i)))
+		}
+		return list
+	case reflect.Map:
+		data, ok := asQueryMap(v)
+		if !ok {
+			return v
+		}
+
+		result := make(QueryMap, len(data))
+		for key, elem := range data {
+			result[key] = p.value(elem, t.Elem(), "", appendPath(path, key))
+		}
+		return result
+	}
+
+	return v
+}
+
+// structure prepares the query `data` of the structure type `t` and returns it with the decoder names.
+func (p *queryPreparer) structure(data QueryMap, t reflect.Type, path []string) QueryMap {
+	result := QueryMap{}
+	p.structureInto(data, t, path, result)
+
+	return result
+}
+
+// structureInto prepares the query `data` of the structure type `t` into `result`,
+// reporting the keys that don't match any field if errorUnused is set.
+func (p *queryPreparer) structureInto(data QueryMap, t reflect.Type, path []string, result QueryMap) {
+	used := map[string]bool{}
+	p.fields(data, t, path, result, used)
+
+	if p.errorUnused {
+		p.reportUnused(data, path, "", used)
+	}
+}
+
+// reportUnused reports the keys of `data` found by the `path` segments that are not `used` by any field.
+// The used keys are bracket paths under the `prefix`, so that the maps read only in part
+// by the bracketed field names ("page[size]") are checked key by key.
+func (p *queryPreparer) reportUnused(data QueryMap, path []string, prefix string, used map[string]bool) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		keyPrefix := joinKey(prefix, key)
+		if used[keyPrefix] {
+			continue
+		}
+		if nested, ok := asQueryMap(data[key]); ok && hasUsedPrefix(used, keyPrefix+"[") {
+			p.reportUnused(nested, appendPath(path, key), keyPrefix, used)
+			continue
+		}
+
+		keyPath := p.path(appendPath(path, key))
+		p.errs = append(
+			p.errs, &FieldError{
+				Path:    keyPath,
+				Value:   data[key],
+				Message: fmt.Sprintf("'%s' is an unknown parameter", keyPath),
+			},
+		)
+	}
+}
+
+// hasUsedPrefix reports whether a used key starts with the `prefix`.
+func hasUsedPrefix(used map[string]bool, prefix string) bool {
+	for key := range used {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fields moves the fields of the structure type `t` from the query `data` into `result`,
+// marking the keys of `data` they were read from as `used`.
+func (p *queryPreparer) fields(data QueryMap, t reflect.Type, path []string, result QueryMap, used map[string]bool) {
+	for _, f := range structFields(t) {
+		fieldPath := append(slices.Clone(path), f.path...)
+
+		if f.inline || f.squash {
+			target := result
+			if !f.squash {
+				target = QueryMap{}
+			}
+
+			if f.inline {
+				p.fields(data, derefType(f.field.Type), path, target, used)
+			} else {
+				nested := QueryMap{}
+				if key, value, ok := findPath(data, f.path); ok {
+					used[key] = true
+					nested, _ = asQueryMap(value)
+				}
+				p.structureInto(nested, derefType(f.field.Type), fieldPath, target)
+			}
+
+			if !f.squash && len(target) > 0 {
+				result[f.key] = target
+			}
+			continue
+		}
+
+		if key, value, ok := findPath(data, f.path); ok {
+			used[key] = true
+			result[f.key] = p.value(value, f.field.Type, f.opts, fieldPath)
+			continue
+		}
+
+		if value, ok := f.opts.Lookup("default"); ok {
+			result[f.key] = p.value(value, f.field.Type, f.opts, fieldPath)
+			continue
+		}
+
+		if f.opts.Contains("required") {
+			p.errs = append(
+				p.errs, &FieldError{
+					Path:    p.path(fieldPath),
+					Type:    f.field.Type.String(),
+					Message: fmt.Sprintf("'%s' is required", p.path(fieldPath)),
+				},
+			)
+			continue
+		}
+
+		// The defaults of an absent structure still apply, unless it's an optional pointer
+		if f.field.Type.Kind() == reflect.Struct && !p.isLeafStruct(f.field.Type) {
+			nested := p.structure(QueryMap{}, f.field.Type, fieldPath)
+			if len(nested) > 0 {
+				result[f.key] = nested
+			}
+		}
+	}
+}
+
+// tagDelimiter returns the delimiter of the list values set by the tag options:
+// `comma`, `pipe`, `space` or `delimiter=x`.
+func tagDelimiter(opts tagOptions) (string, bool) {
+	switch {
+	case opts.Contains("comma"):
+		return string(CommaDelimiter), true
+	case opts.Contains("pipe"):
+		return string(PipeDelimiter), true
+	case opts.Contains("space"):
+		return string(SpaceDelimiter), true
+	}
+
+	delimiter, ok := opts.Lookup("delimiter")
+	return delimiter, ok && delimiter != ""
+}
+
+// isLeafStruct reports whether the structure type is decoded from a single value
+// (by a converter or UnmarshalText) rather than field by field.
+func (p *queryPreparer) isLeafStruct(t reflect.Type) bool {
+	if _, ok := lookupConverter(p.converters, t); ok {
+		return true
+	}
+
+	return reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// asQueryMap returns the map value as QueryMap, false if it's not a map.
+func asQueryMap(v any) (QueryMap, bool) {
+	switch value := v.(type) {
+	case QueryMap:
+		return value, true
+	case map[string]any:
+		return QueryMap(value), true
+	}
+
+	return nil, false
+}
+
+// findKey returns the key of `data` matching the field name the same way mapstructure does:
+// exactly, then case-insensitively.
+func findKey(data QueryMap, name string) (string, bool) {
+	if _, ok := data[name]; ok {
+		return name, true
+	}
+	for key := range data {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+// findPath returns the value of `data` found by the path segments of a field name, matched the same way as findKey,
+// and the bracket path of the keys it was found by: "Page[size]" for the segments "page", "size".
+func findPath(data QueryMap, segments []string) (string, any, bool) {
+	var v any = data
+	keyPath := ""
+	for _, segment := range segments {
+		nested, ok := asQueryMap(v)
+		if !ok {
+			return "", nil, false
+		}
+		key, ok := findKey(nested, segment)
+		if !ok {
+			return "", nil, false
+		}
+		keyPath = joinKey(keyPath, key)
+		v = nested[key]
+	}
+
+	return keyPath, v, true
+}
+
+// appendPath returns a copy of the path segments with the `segment` appended.
+func appendPath(path []string, segment string) []string {
+	return append(slices.Clone(path), segment)
+}
+
+// querySegments translates the path segments of the decoder names into the query names.
+// The segments that don't match a field are kept as they are.
+func querySegments(t reflect.Type, segments []string) []string {
+	var result []string
+
+	for i, segment := range segments {
+		if t == nil {
+			return append(result, segments[i:]...)
+		}
+		t = derefType(t)
+
+		switch t.Kind() {
+		case reflect.Struct:
+			names, fieldType, ok := queryFieldNames(t, segment)
+			if !ok {
+				return append(result, segments[i:]...)
+			}
+			result = append(result, names...)
+			t = fieldType
+		case reflect.Map, reflect.Slice, reflect.Array:
+			result = append(result, segment)
+			t = t.Elem()
+		default:
+			return append(result, segments[i:]...)
+		}
+	}
+
+	return result
+}
+
+// queryFieldNames finds the field of the structure type `t` by its decoder name `key`
+// and returns its query path segments (none for inline structures) and its type.
+func queryFieldNames(t reflect.Type, key string) ([]string, reflect.Type, bool) {
+	for _, f := range structFields(t) {
+		if f.squash {
+			names, fieldType, ok := queryFieldNames(derefType(f.field.Type), key)
+			if !ok {
+				continue
+			}
+			if !f.inline {
+				names = append(slices.Clone(f.path), names...)
+			}
+			return names, fieldType, true
+		}
+
+		if f.key != key {
+			continue
+		}
+		if f.inline {
+			return nil, f.field.Type, true
+		}
+		return f.path, f.field.Type, true
+	}
+
+	return nil, nil, false
+}
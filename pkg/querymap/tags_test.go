@@ -0,0 +1,330 @@
+package querymap
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+type TestDefaultsPage struct {
+	Size   int `json:"size" query:"size,default=20"`
+	Number int `json:"number" query:"number,default=1"`
+}
+
+type TestDefaultsFilter struct {
+	Field string `json:"field" query:"field,required"`
+	Op    string `json:"op" query:"op,default=eq"`
+}
+
+type TestDefaultsParams struct {
+	ID      string               `json:"id" query:"id,required"`
+	Limit   int                  `json:"limit" query:"limit,default=20"`
+	Page    TestDefaultsPage     `json:"page"`
+	Cursor  *TestDefaultsPage    `json:"cursor"`
+	Filters []TestDefaultsFilter `json:"filters"`
+}
+
+func TestToStructDefaults(t *testing.T) {
+	values, err := url.ParseQuery("id=7&page[number]=3&filters[0][field]=name")
+	panicIfErr(err)
+
+	m := FromValues(values)
+	got, err := ToStruct[TestDefaultsParams](m)
+	panicIfErr(err)
+
+	want := &TestDefaultsParams{
+		ID:      "7",
+		Limit:   20,
+		Page:    TestDefaultsPage{Size: 20, Number: 3},
+		Filters: []TestDefaultsFilter{{Field: "name", Op: "eq"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ToStruct() = %+v, want %+v", got, want)
+	}
+
+	if _, ok := m["limit"]; ok {
+		t.Errorf("Expected ToStruct not to modify the QueryMap, got %v", m)
+	}
+}
+
+func TestToStructDefaultsAbsentNested(t *testing.T) {
+	got, err := ToStruct[TestDefaultsParams](QueryMap{"id": "7", "limit": "5"})
+	panicIfErr(err)
+
+	want := &TestDefaultsParams{ID: "7", Limit: 5, Page: TestDefaultsPage{Size: 20, Number: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ToStruct() = %+v, want %+v", got, want)
+	}
+}
+
+func TestToStructRequired(t *testing.T) {
+	values, err := url.ParseQuery("limit=x&filters[0][op]=gt")
+	panicIfErr(err)
+
+	_, err = FromValuesToStruct[TestDefaultsParams](values)
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected *DecodeError, got %v", err)
+	}
+
+	got := map[string]string{}
+	for _, fieldErr := range decodeErr.Errors {
+		got[fieldErr.Path] = fieldErr.Message
+	}
+	want := map[string]string{
+		"id":                "'id' is required",
+		"filters[0][field]": "'filters[0][field]' is required",
+		"limit":             "cannot parse 'limit' as int: strconv.ParseInt: parsing \"x\": invalid syntax",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeError.Errors = %v, want %v", got, want)
+	}
+}
+
+func TestToStructRequiredErrorUnset(t *testing.T) {
+	_, err := ToStructWithOptions[TestDefaultsParams](QueryMap{"limit": "1"}, DecodeOptions{ErrorUnset: true})
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected *DecodeError, got %v", err)
+	}
+
+	paths := map[string]int{}
+	for _, fieldErr := range decodeErr.Errors {
+		paths[fieldErr.Path]++
+	}
+	if paths["id"] != 1 {
+		t.Errorf("Expected 'id' to be reported once, got %v", decodeErr.Errors)
+	}
+}
+
+func TestTagOptionsLookup(t *testing.T) {
+	_, opts := parseTag("limit,required,default=20")
+
+	if value, ok := opts.Lookup("default"); !ok || value != "20" {
+		t.Errorf("Lookup(default) = %q, %v, want \"20\", true", value, ok)
+	}
+	if _, ok := opts.Lookup("required"); ok {
+		t.Errorf("Expected Lookup(required) to be false")
+	}
+}
+
+type TestTagsPagination struct {
+	Size   int `query:"size" json:"pageSize"`
+	Number int `query:"number" json:"pageNumber"`
+}
+
+type TestTagsSort struct {
+	SortBy string `query:"sort_by"`
+	Desc   bool   `query:"desc"`
+}
+
+type TestTagsParams struct {
+	TestTagsSort `query:",inline"`
+
+	Query      string             `query:"q" json:"query"`
+	Pagination TestTagsPagination `query:"page" json:"pagination"`
+	Tags       []string           `query:"tags,comma" json:"tags"`
+	IDs        []int              `query:"ids,comma"`
+	Owner      string             `json:"owner"`
+	Internal   string             `query:"-" json:"internal"`
+}
+
+func TestToStructQueryTag(t *testing.T) {
+	values, err := url.ParseQuery(
+		"q=go&page[size]=10&page[number]=2&tags=a,b&tags=c&ids=1,2&owner=ken&sort_by=name&desc=true&internal=x",
+	)
+	panicIfErr(err)
+
+	got, err := FromValuesToStruct[TestTagsParams](values)
+	panicIfErr(err)
+
+	want := &TestTagsParams{
+		TestTagsSort: TestTagsSort{SortBy: "name", Desc: true},
+		Query:        "go",
+		Pagination:   TestTagsPagination{Size: 10, Number: 2},
+		Tags:         []string{"a", "b", "c"},
+		IDs:          []int{1, 2},
+		Owner:        "ken",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FromValuesToStruct() = %+v, want %+v", got, want)
+	}
+}
+
+func TestToStructQueryTagIgnoresJSONNames(t *testing.T) {
+	values, err := url.ParseQuery("query=go&pagination[pageSize]=10&TestTagsSort[sort_by]=name")
+	panicIfErr(err)
+
+	_, err = ToStructStrict[TestTagsParams](FromValues(values))
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected *DecodeError, got %v", err)
+	}
+
+	got := map[string]any{}
+	for _, fieldErr := range decodeErr.Errors {
+		got[fieldErr.Path] = fieldErr.Value
+	}
+	want := map[string]any{
+		"query":        "go",
+		"pagination":   QueryMap{"pageSize": "10"},
+		"TestTagsSort": QueryMap{"sort_by": "name"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeError.Errors = %v, want %v", got, want)
+	}
+}
+
+func TestToStructQueryTagErrorPath(t *testing.T) {
+	values, err := url.ParseQuery("page[size]=ten&desc=maybe&ids=1,x")
+	panicIfErr(err)
+
+	_, err = FromValuesToStruct[TestTagsParams](values)
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected *DecodeError, got %v", err)
+	}
+
+	got := map[string]string{}
+	for _, fieldErr := range decodeErr.Errors {
+		got[fieldErr.Path] = fieldErr.Type
+	}
+	want := map[string]string{"page[size]": "int", "desc": "bool", "ids[1]": "int"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeError.Errors = %v, want %v", got, want)
+	}
+}
+
+func TestFromStructQueryTag(t *testing.T) {
+	params := TestTagsParams{
+		TestTagsSort: TestTagsSort{SortBy: "name"},
+		Query:        "go",
+		Pagination:   TestTagsPagination{Size: 10},
+		Tags:         []string{"a", "b"},
+		IDs:          []int{1, 2},
+		Internal:     "x",
+	}
+
+	got, err := FromStruct(params)
+	panicIfErr(err)
+
+	want := QueryMap{
+		"sort_by": "name",
+		"desc":    "false",
+		"q":       "go",
+		"page":    QueryMap{"size": "10", "number": "0"},
+		"tags":    "a,b",
+		"ids":     "1,2",
+		"owner":   "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FromStruct() = %v, want %v", got, want)
+	}
+
+	decoded, err := FromValuesToStruct[TestTagsParams](got.ToValues())
+	panicIfErr(err)
+
+	params.Internal = ""
+	if !reflect.DeepEqual(*decoded, params) {
+		t.Errorf("Round trip = %+v, want %+v", *decoded, params)
+	}
+}
+
+func TestToStructDelimiterTags(t *testing.T) {
+	type Params struct {
+		Comma  []string `query:"comma,comma"`
+		Pipe   []int    `query:"pipe,pipe"`
+		Space  []string `query:"space,space"`
+		Custom []string `query:"custom,delimiter=;"`
+		Plain  []string `query:"plain"`
+		Levels []int    `query:"levels,pipe,default=1|2"`
+	}
+
+	values, err := url.ParseQuery("comma=a,b&pipe=1|2|3&space=x+y&custom=a%3Bb&plain=a,b")
+	panicIfErr(err)
+
+	got, err := FromValuesToStruct[Params](values)
+	panicIfErr(err)
+
+	want := &Params{
+		Comma:  []string{"a", "b"},
+		Pipe:   []int{1, 2, 3},
+		Space:  []string{"x", "y"},
+		Custom: []string{"a", "b"},
+		Plain:  []string{"a,b"},
+		Levels: []int{1, 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FromValuesToStruct() = %+v, want %+v", got, want)
+	}
+
+	encoded, err := FromStruct(*want)
+	panicIfErr(err)
+
+	wantEncoded := QueryMap{
+		"comma":  "a,b",
+		"pipe":   "1|2|3",
+		"space":  "x y",
+		"custom": "a;b",
+		"plain":  []string{"a,b"},
+		"levels": "1|2",
+	}
+	if !reflect.DeepEqual(encoded, wantEncoded) {
+		t.Errorf("FromStruct() = %v, want %v", encoded, wantEncoded)
+	}
+}
+
+type TestTagsBracketParams struct {
+	Size   int    `query:"page[size],required"`
+	Number int    `query:"page[number],default=1"`
+	Sort   string `query:"sort[by]"`
+	Nested struct {
+		Min int `query:"price[min]"`
+	} `query:"filter"`
+}
+
+func TestToStructQueryTagBrackets(t *testing.T) {
+	values, err := url.ParseQuery("page[size]=5&sort[by]=name&filter[price][min]=10")
+	panicIfErr(err)
+
+	got, err := ToStructStrict[TestTagsBracketParams](FromValues(values))
+	panicIfErr(err)
+
+	want := &TestTagsBracketParams{Size: 5, Number: 1, Sort: "name"}
+	want.Nested.Min = 10
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ToStructStrict() = %+v, want %+v", got, want)
+	}
+
+	encoded, err := FromStruct(*want)
+	panicIfErr(err)
+	wantEncoded := QueryMap{
+		"page":   QueryMap{"size": "5", "number": "1"},
+		"sort":   QueryMap{"by": "name"},
+		"filter": QueryMap{"price": QueryMap{"min": "10"}},
+	}
+	if !reflect.DeepEqual(encoded, wantEncoded) {
+		t.Errorf("FromStruct() = %v, want %v", encoded, wantEncoded)
+	}
+}
+
+func TestToStructQueryTagBracketsErrors(t *testing.T) {
+	values, err := url.ParseQuery("page[sise]=5&page[number]=x&filter[price][min]=1&filter[price][max]=1")
+	panicIfErr(err)
+
+	_, err = ToStructStrict[TestTagsBracketParams](FromValues(values))
+
+	const exceptedMessage = "4 error(s) decoding:\n\n" +
+		"* 'filter[price][max]' is an unknown parameter\n" +
+		"* 'page[sise]' is an unknown parameter\n" +
+		"* 'page[size]' is required\n" +
+		"* cannot parse 'page[number]' as int: strconv.ParseInt: parsing \"x\": invalid syntax"
+	if err == nil || err.Error() != exceptedMessage {
+		t.Errorf("Expected error message to be '%s', got '%v'", exceptedMessage, err)
+	}
+}